@@ -4,7 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ssrdive/mysequel"
@@ -12,24 +16,27 @@ import (
 	"github.com/ssrdive/scribe/queries"
 )
 
-// AccountModel struct holds database instance
+// ErrApprovalRequired is returned when posting directly while approval
+// is required
+var ErrApprovalRequired = errors.New("postings require approval, create a draft instead")
+
+// AccountModel struct holds database instance. When RequireApproval is
+// set, only approved drafts are posted: journal entries, payment vouchers
+// and deposits go through drafts and every other posting method returns
+// ErrApprovalRequired. Branch selects the document numbering series.
+// ReceivableAccountID is the accounts receivable control account whose
+// contract entries make up the receivables subledger. PayableAccountID is
+// the accounts payable control account vendor bills are posted to.
 type AccountModel struct {
-	DB *sql.DB
+	DB                  *sql.DB
+	RequireApproval     bool
+	Branch              string
+	ReceivableAccountID int
+	PayableAccountID    int
 }
 
 func validatePostingDate(postingDate string) error {
-	now := time.Now()
-
-	var m time.Month
-	year, m, _ := now.Date()
-	month := int(m)
-
-	var oldestDate time.Time
-	if month < 4 {
-		oldestDate = time.Date(year-1, 4, 1, 0, 0, 0, 0, time.UTC)
-	} else {
-		oldestDate = time.Date(year, 4, 1, 0, 0, 0, 0, time.UTC)
-	}
+	oldestDate := time.Date(fiscalYear(time.Now()), FiscalYearStartMonth, 1, 0, 0, 0, 0, time.UTC)
 
 	parsedDate, err := time.Parse("2006-01-02", postingDate)
 	if err != nil {
@@ -43,6 +50,46 @@ func validatePostingDate(postingDate string) error {
 	}
 }
 
+// parseAmount parses a non-negative monetary amount into cents
+func parseAmount(amount string) (int64, error) {
+	cents, err := parseSignedAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	if cents < 0 {
+		return 0, fmt.Errorf("amount %q is negative", amount)
+	}
+	return cents, nil
+}
+
+// parseSignedAmount parses a monetary amount, such as a balance read back
+// from the database, into cents
+func parseSignedAmount(amount string) (int64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	return toCents(f), nil
+}
+
+// formatAmount formats cents as a decimal amount
+func formatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
 func CreateTransaction(tx *sql.Tx, userID, postingDate, contractID, remark string) (int64, error) {
 	err := validatePostingDate(postingDate)
 	if err != nil {
@@ -62,7 +109,8 @@ func CreateTransaction(tx *sql.Tx, userID, postingDate, contractID, remark strin
 	return tid, err
 }
 
-// IssueJournalEntries issues journal entries
+// IssueJournalEntries issues journal entries and records the transaction
+// in the transaction log
 func IssueJournalEntries(tx *sql.Tx, tid int64, journalEntries []models.JournalEntry) error {
 	for _, entry := range journalEntries {
 		if len(entry.Debit) != 0 {
@@ -88,11 +136,17 @@ func IssueJournalEntries(tx *sql.Tx, tid int64, journalEntries []models.JournalE
 			}
 		}
 	}
-	return nil
+	return appendTransactionLog(tx, tid, LogPost)
 }
 
 // CreateAccount creates an account
 func (m *AccountModel) CreateAccount(rParams, oParams []string, form url.Values) (int64, error) {
+	return m.CreateAccountAs("", rParams, oParams, form)
+}
+
+// CreateAccountAs creates an account and records it in the change history
+// against the user
+func (m *AccountModel) CreateAccountAs(userID string, rParams, oParams []string, form url.Values) (int64, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -106,13 +160,19 @@ func (m *AccountModel) CreateAccount(rParams, oParams []string, form url.Values)
 	}()
 
 	form.Set("datetime", time.Now().Format("2006-01-02 15:04:05"))
-	cid, err := mysequel.Insert(mysequel.FormTable{
+	ft := mysequel.FormTable{
 		TableName: "account",
 		RCols:     rParams,
 		OCols:     oParams,
 		Form:      form,
 		Tx:        tx,
-	})
+	}
+	cid, err := mysequel.Insert(ft)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, ft.TableName, cid, ft.Cols(), ft.Values())
 	if err != nil {
 		return 0, err
 	}
@@ -120,8 +180,16 @@ func (m *AccountModel) CreateAccount(rParams, oParams []string, form url.Values)
 	return cid, nil
 }
 
-// CreateCategory creates a category
+// CreateCategory creates a category. A category may be nested under another
+// category by passing parent_id, in which case it inherits the parent's
+// sub account
 func (m *AccountModel) CreateCategory(rParams, oParams []string, form url.Values) (int64, error) {
+	return m.CreateCategoryAs("", rParams, oParams, form)
+}
+
+// CreateCategoryAs creates a category as CreateCategory does and records
+// it in the change history against the user
+func (m *AccountModel) CreateCategoryAs(userID string, rParams, oParams []string, form url.Values) (int64, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -134,14 +202,32 @@ func (m *AccountModel) CreateCategory(rParams, oParams []string, form url.Values
 		_ = tx.Commit()
 	}()
 
+	if parentID := form.Get("parent_id"); parentID != "" {
+		var subAccountID string
+		err = tx.QueryRow(queries.CategorySubAccount, parentID).Scan(&subAccountID)
+		if err == sql.ErrNoRows {
+			err = errors.New("parent category does not exist")
+		}
+		if err != nil {
+			return 0, err
+		}
+		form.Set("sub_account_id", subAccountID)
+	}
+
 	form.Set("datetime", time.Now().Format("2006-01-02 15:04:05"))
-	cid, err := mysequel.Insert(mysequel.FormTable{
+	ft := mysequel.FormTable{
 		TableName: "account_category",
 		RCols:     rParams,
 		OCols:     oParams,
 		Form:      form,
 		Tx:        tx,
-	})
+	}
+	cid, err := mysequel.Insert(ft)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, ft.TableName, cid, ft.Cols(), ft.Values())
 	if err != nil {
 		return 0, err
 	}
@@ -193,19 +279,12 @@ func (m *AccountModel) AccountsForPNL(startDate, endDate string) ([]models.Accou
 	return res, nil
 }
 
-// ChartOfAccounts returns chart of accounts
-func (m *AccountModel) ChartOfAccounts() ([]models.ChartOfAccount, error) {
-	var res []models.ChartOfAccount
-	err := mysequel.QueryToStructs(&res, m.DB, queries.ChartOfAccounts)
-	if err != nil {
-		return nil, err
-	}
-
-	return res, nil
-}
-
 // PaymentVoucher creates payment voucher
 func (m *AccountModel) PaymentVoucher(userID, postingDate, fromAccountID, amount, entries, remark, dueDate, checkNumber, payee string) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -218,7 +297,17 @@ func (m *AccountModel) PaymentVoucher(userID, postingDate, fromAccountID, amount
 		_ = tx.Commit()
 	}()
 
-	err = validatePostingDate(postingDate)
+	tid, err := issuePaymentVoucher(tx, m.Branch, userID, postingDate, fromAccountID, amount, entries, remark, dueDate, checkNumber, payee)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// issuePaymentVoucher posts a payment voucher within a transaction
+func issuePaymentVoucher(tx *sql.Tx, branch, userID, postingDate, fromAccountID, amount, entries, remark, dueDate, checkNumber, payee string) (int64, error) {
+	err := validatePostingDate(postingDate)
 	if err != nil {
 		return 0, err
 	}
@@ -233,18 +322,32 @@ func (m *AccountModel) PaymentVoucher(userID, postingDate, fromAccountID, amount
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
-	_, err = mysequel.Insert(mysequel.Table{
+	_, err = allocateDocumentNumber(tx, DocumentPaymentVoucher, branch, postingDate, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	pv := mysequel.Table{
 		TableName: "payment_voucher",
 		Columns:   []string{"transaction_id", "due_date", "check_number", "payee"},
 		Vals:      []interface{}{tid, dueDate, checkNumber, payee},
 		Tx:        tx,
-	})
+	}
+	pid, err := mysequel.Insert(pv)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, pv.TableName, pid, pv.Columns, pv.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	err = registerCheque(tx, userID, fromAccountID, checkNumber, pid, tid, amount, postingDate)
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
@@ -255,7 +358,6 @@ func (m *AccountModel) PaymentVoucher(userID, postingDate, fromAccountID, amount
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
@@ -267,15 +369,23 @@ func (m *AccountModel) PaymentVoucher(userID, postingDate, fromAccountID, amount
 			Tx:        tx,
 		})
 		if err != nil {
-			_ = tx.Rollback()
 			return 0, err
 		}
 	}
+
+	err = appendTransactionLog(tx, tid, LogPost)
+	if err != nil {
+		return 0, err
+	}
 	return tid, nil
 }
 
 // Deposit enters bank deposits
 func (m *AccountModel) Deposit(userID, postingDate, toAccountID, amount, entries, remark string) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -288,7 +398,17 @@ func (m *AccountModel) Deposit(userID, postingDate, toAccountID, amount, entries
 		_ = tx.Commit()
 	}()
 
-	err = validatePostingDate(postingDate)
+	tid, err := issueDeposit(tx, m.Branch, userID, postingDate, toAccountID, amount, entries, remark)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// issueDeposit posts a deposit within a transaction
+func issueDeposit(tx *sql.Tx, branch, userID, postingDate, toAccountID, amount, entries, remark string) (int64, error) {
+	err := validatePostingDate(postingDate)
 	if err != nil {
 		return 0, err
 	}
@@ -303,7 +423,11 @@ func (m *AccountModel) Deposit(userID, postingDate, toAccountID, amount, entries
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
+		return 0, err
+	}
+
+	_, err = allocateDocumentNumber(tx, DocumentDeposit, branch, postingDate, tid)
+	if err != nil {
 		return 0, err
 	}
 
@@ -314,7 +438,6 @@ func (m *AccountModel) Deposit(userID, postingDate, toAccountID, amount, entries
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
@@ -325,27 +448,41 @@ func (m *AccountModel) Deposit(userID, postingDate, toAccountID, amount, entries
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
 	for _, entry := range paymentVoucher {
-		_, err := mysequel.Insert(mysequel.Table{
+		_, err = mysequel.Insert(mysequel.Table{
 			TableName: "account_transaction",
 			Columns:   []string{"transaction_id", "account_id", "type", "amount"},
 			Vals:      []interface{}{tid, entry.Account, "CR", entry.Amount},
 			Tx:        tx,
 		})
 		if err != nil {
-			_ = tx.Rollback()
 			return 0, err
 		}
 	}
+
+	err = appendTransactionLog(tx, tid, LogPost)
+	if err != nil {
+		return 0, err
+	}
 	return tid, nil
 }
 
 // JournalEntry issues journal entries
 func (m *AccountModel) JournalEntry(userID, postingDate, remark, entries string) (int64, error) {
+	return m.ReversingJournalEntry(userID, postingDate, remark, entries, "")
+}
+
+// ReversingJournalEntry issues journal entries. Accruals can set reverseOn
+// to have a reversing transaction posted on that date, usually the first
+// day of the next month.
+func (m *AccountModel) ReversingJournalEntry(userID, postingDate, remark, entries, reverseOn string) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -358,11 +495,29 @@ func (m *AccountModel) JournalEntry(userID, postingDate, remark, entries string)
 		_ = tx.Commit()
 	}()
 
-	err = validatePostingDate(postingDate)
+	tid, err := issueJournalEntry(tx, m.Branch, userID, postingDate, remark, entries, reverseOn)
 	if err != nil {
 		return 0, err
 	}
 
+	return tid, nil
+}
+
+// issueJournalEntry posts journal entries within a transaction. When
+// reverseOn is set a linked reversing transaction is posted on that date.
+func issueJournalEntry(tx *sql.Tx, branch, userID, postingDate, remark, entries, reverseOn string) (int64, error) {
+	err := validatePostingDate(postingDate)
+	if err != nil {
+		return 0, err
+	}
+
+	if reverseOn != "" {
+		err = validateReversalDate(postingDate, reverseOn)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	var journalEntries []models.JournalEntry
 	_ = json.Unmarshal([]byte(entries), &journalEntries)
 
@@ -373,17 +528,26 @@ func (m *AccountModel) JournalEntry(userID, postingDate, remark, entries string)
 		Tx:        tx,
 	})
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
-	err = IssueJournalEntries(tx, tid, journalEntries)
+	_, err = allocateDocumentNumber(tx, DocumentJournal, branch, postingDate, tid)
+	if err != nil {
+		return 0, err
+	}
 
+	err = IssueJournalEntries(tx, tid, journalEntries)
 	if err != nil {
-		_ = tx.Rollback()
 		return 0, err
 	}
 
+	if reverseOn != "" {
+		_, err = issueReversal(tx, branch, userID, tid, reverseOn, remark, journalEntries)
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	return tid, nil
 }
 
@@ -398,6 +562,36 @@ func (m *AccountModel) Transaction(aid int) ([]models.Transaction, error) {
 	return res, nil
 }
 
+// TransactionPage returns a page of the entries of a transaction
+func (m *AccountModel) TransactionPage(tid int, cursor string, limit int) (models.TransactionPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.TransactionCount, tid).Scan(&c.Total)
+		if err != nil {
+			return models.TransactionPage{}, err
+		}
+	}
+
+	var res []models.Transaction
+	args := append([]interface{}{tid}, c.args(1)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.TransactionPage, append(args, size+1)...)
+	if err != nil {
+		return models.TransactionPage{}, err
+	}
+
+	page := models.TransactionPage{Entries: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Entries = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
 // Ledger returns account ledger
 func (m *AccountModel) Ledger(aid int) ([]models.LedgerEntry, error) {
 	var res []models.LedgerEntry
@@ -409,6 +603,37 @@ func (m *AccountModel) Ledger(aid int) ([]models.LedgerEntry, error) {
 	return res, nil
 }
 
+// LedgerPage returns a page of the account ledger ordered by posting date
+func (m *AccountModel) LedgerPage(aid int, cursor string, limit int) (models.LedgerPage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.LedgerPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.AccountLedgerCount, aid).Scan(&c.Total)
+		if err != nil {
+			return models.LedgerPage{}, err
+		}
+	}
+
+	var res []models.LedgerEntry
+	args := append([]interface{}{aid}, c.args(3)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.AccountLedgerPage, append(args, size+1)...)
+	if err != nil {
+		return models.LedgerPage{}, err
+	}
+
+	page := models.LedgerPage{Entries: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Entries = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.PostingDate, last.TransactionID, last.ID)
+	}
+	return page, nil
+}
+
 // PaymentVouchers returns payment vouchers
 func (m *AccountModel) PaymentVouchers() ([]models.PaymentVoucherList, error) {
 	var res []models.PaymentVoucherList
@@ -420,10 +645,39 @@ func (m *AccountModel) PaymentVouchers() ([]models.PaymentVoucherList, error) {
 	return res, nil
 }
 
+// PaymentVouchersPage returns a page of payment vouchers, newest first
+func (m *AccountModel) PaymentVouchersPage(cursor string, limit int) (models.PaymentVoucherPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.PaymentVoucherPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.PaymentVouchersCount).Scan(&c.Total)
+		if err != nil {
+			return models.PaymentVoucherPage{}, err
+		}
+	}
+
+	var res []models.PaymentVoucherList
+	err = mysequel.QueryToStructs(&res, m.DB, queries.PaymentVouchersPage, append(c.args(1), size+1)...)
+	if err != nil {
+		return models.PaymentVoucherPage{}, err
+	}
+
+	page := models.PaymentVoucherPage{PaymentVouchers: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.PaymentVouchers = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
 // PaymentVoucherDetails returns payment voucher details
 func (m *AccountModel) PaymentVoucherDetails(pid int) (models.PaymentVoucherSummary, error) {
-	var dueDate, checkNumber, payee, remark, account, datetime sql.NullString
-	err := m.DB.QueryRow(queries.PaymentVoucherCheckDetails, pid).Scan(&dueDate, &checkNumber, &payee, &remark, &account, &datetime)
+	var dueDate, checkNumber, payee, remark, account, datetime, documentNumber sql.NullString
+	err := m.DB.QueryRow(queries.PaymentVoucherCheckDetails, pid).Scan(&dueDate, &checkNumber, &payee, &remark, &account, &datetime, &documentNumber)
 
 	var vouchers []models.PaymentVoucherDetails
 	err = mysequel.QueryToStructs(&vouchers, m.DB, queries.PaymentVoucherDetails, pid)
@@ -431,9 +685,14 @@ func (m *AccountModel) PaymentVoucherDetails(pid int) (models.PaymentVoucherSumm
 		return models.PaymentVoucherSummary{}, err
 	}
 
-	return models.PaymentVoucherSummary{DueDate: dueDate, CheckNumber: checkNumber, Payee: payee, Remark: remark, Account: account, Datetime: datetime, PaymentVoucherDetails: vouchers}, nil
+	return models.PaymentVoucherSummary{DueDate: dueDate, CheckNumber: checkNumber, Payee: payee, Remark: remark, Account: account, Datetime: datetime, DocumentNumber: documentNumber, PaymentVoucherDetails: vouchers}, nil
 }
 
+// JournalEntriesForAudit returns the journal entries made on date and
+// posted on postingDate, either of which may be empty.
+//
+// Deprecated: use AuditTrail, which filters on entry and posting date
+// ranges as well as user, account, amount, remark and transaction type.
 func (m *AccountModel) JournalEntriesForAudit(date, postingDate string) ([]models.JEsForAudit, error) {
 	var d, pDate sql.NullString
 	if date == "" {
@@ -461,3 +720,42 @@ func (m *AccountModel) JournalEntriesForAudit(date, postingDate string) ([]model
 
 	return res, nil
 }
+
+// JournalEntriesForAuditPage returns a page of journal entries for audit
+// ordered by transaction with debits before credits.
+//
+// Deprecated: use AuditTrail.
+func (m *AccountModel) JournalEntriesForAuditPage(date, postingDate, cursor string, limit int) (models.JEsForAuditPage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.JEsForAuditPage{}, err
+	}
+	size := pageSize(limit)
+
+	d, pDate := mysequel.NewNullString(date), mysequel.NewNullString(postingDate)
+	if c.first() {
+		err = m.DB.QueryRow(queries.JournalEntriesForAuditCount, d, d, pDate, pDate).Scan(&c.Total)
+		if err != nil {
+			return models.JEsForAuditPage{}, err
+		}
+	}
+
+	var res []models.JEsForAudit
+	args := append([]interface{}{d, d, pDate, pDate}, c.args(3)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.JournalEntriesForAuditPage, append(args, size+1)...)
+	if err != nil {
+		return models.JEsForAuditPage{}, err
+	}
+
+	page := models.JEsForAuditPage{Entries: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Entries = res[:size]
+		last := res[size-1]
+		typeOrder := 1
+		if last.Type == "DR" {
+			typeOrder = 0
+		}
+		page.NextCursor = encodeCursor(c.Total, last.TransactionID, typeOrder, last.ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,231 @@
+package scribe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/scribe/export"
+	"github.com/ssrdive/scribe/models"
+)
+
+// DefaultAgingLimits bucket items as current, 1-30, 31-60, 61-90 and over
+// 90 days past due
+var DefaultAgingLimits = []int{0, 30, 60, 90}
+
+// agingBuckets names the buckets of the ascending upper day limits. Items
+// beyond the last limit fall in an extra bucket.
+func agingBuckets(limits []int) ([]string, error) {
+	if len(limits) == 0 {
+		return nil, errors.New("aging limits are required")
+	}
+
+	names := make([]string, 0, len(limits)+1)
+	for i, l := range limits {
+		if l < 0 || (i > 0 && l <= limits[i-1]) {
+			return nil, errors.New("aging limits must be ascending and not negative")
+		}
+		switch {
+		case i == 0 && l == 0:
+			names = append(names, "Current")
+		case i == 0:
+			names = append(names, fmt.Sprintf("Up to %d", l))
+		default:
+			names = append(names, fmt.Sprintf("%d-%d", limits[i-1]+1, l))
+		}
+	}
+	return append(names, fmt.Sprintf("%d+", limits[len(limits)-1])), nil
+}
+
+// agingBucket returns the bucket of an item the given days past due
+func agingBucket(limits []int, days int) int {
+	for i, l := range limits {
+		if days <= l {
+			return i
+		}
+	}
+	return len(limits)
+}
+
+// agingItem is an open item to be aged. Items are grouped by party and
+// name is shown alongside it. Outstanding is negative for unapplied
+// receipts and credits.
+type agingItem struct {
+	party         string
+	name          string
+	entryID       int
+	transactionID int
+	postingDate   string
+	dueDate       string
+	remark        string
+	outstanding   int64
+}
+
+// agingReport ages the items as of date. Items must be ordered by party.
+// Summary reports have a row per party and detail reports a row per item.
+func agingReport(date string, limits []int, detail bool, items []agingItem) (models.AgingReport, error) {
+	report := models.AgingReport{Date: date, Detail: detail, Rows: []models.AgingRow{}}
+
+	names, err := agingBuckets(limits)
+	if err != nil {
+		return report, err
+	}
+	report.Buckets = names
+	asOf, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return report, errors.New("invalid aging date")
+	}
+
+	// Sums hold the buckets in cents followed by the unapplied amount
+	n := len(names)
+	fill := func(r *models.AgingRow, sums []int64) {
+		r.Buckets = make([]float64, n)
+		var total int64
+		for i, c := range sums {
+			total += c
+			if i < n {
+				r.Buckets[i] = fromCents(c)
+			}
+		}
+		r.Unapplied = fromCents(sums[n])
+		r.Total = fromCents(total)
+	}
+
+	totals := make([]int64, n+1)
+	var row models.AgingRow
+	var party []int64
+	for _, it := range items {
+		if it.outstanding == 0 {
+			continue
+		}
+		due, err := time.Parse("2006-01-02", it.dueDate)
+		if err != nil {
+			return report, err
+		}
+		days := int(asOf.Sub(due).Hours() / 24)
+		b := n
+		if it.outstanding > 0 {
+			b = agingBucket(limits, days)
+		}
+		totals[b] += it.outstanding
+
+		if detail {
+			r := models.AgingRow{Party: it.party, PartyName: it.name, EntryID: it.entryID, TransactionID: it.transactionID, PostingDate: it.postingDate,
+				DueDate: it.dueDate, Remark: it.remark, Days: days}
+			sums := make([]int64, n+1)
+			sums[b] = it.outstanding
+			fill(&r, sums)
+			report.Rows = append(report.Rows, r)
+			continue
+		}
+
+		if party == nil || row.Party != it.party {
+			if party != nil {
+				fill(&row, party)
+				report.Rows = append(report.Rows, row)
+			}
+			row = models.AgingRow{Party: it.party, PartyName: it.name}
+			party = make([]int64, n+1)
+		}
+		party[b] += it.outstanding
+	}
+	if party != nil {
+		fill(&row, party)
+		report.Rows = append(report.Rows, row)
+	}
+
+	var total models.AgingRow
+	fill(&total, totals)
+	report.Totals, report.Unapplied, report.Total = total.Buckets, total.Unapplied, total.Total
+	return report, nil
+}
+
+// ReceivablesAging ages the outstanding receivables of every contract as
+// of a date into the buckets of the day limits, DefaultAgingLimits when
+// nil. Charges are due on their posting date. Detail reports list every
+// open charge and unapplied credit.
+func (m *AccountModel) ReceivablesAging(date string, limits []int, detail bool) (models.AgingReport, error) {
+	if limits == nil {
+		limits = DefaultAgingLimits
+	}
+
+	open, err := m.OpenReceivables("", date)
+	if err != nil {
+		return models.AgingReport{}, err
+	}
+
+	items := make([]agingItem, len(open))
+	for i, o := range open {
+		outstanding := toCents(o.Outstanding)
+		if o.Type == "CR" {
+			outstanding = -outstanding
+		}
+		items[i] = agingItem{party: o.ContractID, entryID: o.EntryID, transactionID: o.TransactionID, postingDate: o.PostingDate,
+			dueDate: o.PostingDate, remark: o.Remark, outstanding: outstanding}
+	}
+
+	return agingReport(date, limits, detail, items)
+}
+
+// writeAging writes an aging report with subtotals by party in detail
+// reports. Party names are written when nameTitle is set.
+func writeAging(w export.Writer, meta export.Metadata, partyTitle, nameTitle string, report models.AgingReport) error {
+	columns := []export.Column{{Title: partyTitle}}
+	if nameTitle != "" {
+		columns = append(columns, export.Column{Title: nameTitle})
+	}
+	detail := len(columns)
+	if report.Detail {
+		columns = append(columns, export.Column{Title: "Due Date"}, export.Column{Title: "Transaction"}, export.Column{Title: "Remark"}, export.Column{Title: "Days"})
+	}
+	first := len(columns)
+	var numeric []int
+	for _, b := range report.Buckets {
+		numeric = append(numeric, len(columns))
+		columns = append(columns, export.Column{Title: b, Numeric: true})
+	}
+	numeric = append(numeric, len(columns), len(columns)+1)
+	columns = append(columns, export.Column{Title: "Unapplied", Numeric: true}, export.Column{Title: "Total", Numeric: true})
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	groups := 0
+	if report.Detail {
+		groups = 1
+	}
+	s := newSubtotaler(w, groups, numeric)
+	for _, r := range report.Rows {
+		cells := make([]string, len(columns))
+		cells[0] = r.Party
+		if nameTitle != "" {
+			cells[1] = r.PartyName
+		}
+		if report.Detail {
+			cells[detail], cells[detail+1], cells[detail+2], cells[detail+3] = r.DueDate, strconv.Itoa(r.TransactionID), r.Remark, strconv.Itoa(r.Days)
+		}
+		for i, b := range r.Buckets {
+			cells[first+i] = formatAmount(toCents(b))
+		}
+		cells[len(cells)-2] = formatAmount(toCents(r.Unapplied))
+		cells[len(cells)-1] = formatAmount(toCents(r.Total))
+		if err := s.row(len(columns), cells); err != nil {
+			return err
+		}
+	}
+	if err := s.close(len(columns)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportReceivablesAging writes the receivables aging report by contract
+func (m *AccountModel) ExportReceivablesAging(w export.Writer, meta export.Metadata, date string, limits []int, detail bool) error {
+	report, err := m.ReceivablesAging(date, limits, detail)
+	if err != nil {
+		return err
+	}
+
+	return writeAging(w, meta, "Contract", "", report)
+}
@@ -0,0 +1,128 @@
+package scribe
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+func TestAgingBuckets(t *testing.T) {
+	tests := []struct {
+		limits []int
+		want   []string
+	}{
+		{DefaultAgingLimits, []string{"Current", "1-30", "31-60", "61-90", "90+"}},
+		{[]int{15, 45}, []string{"Up to 15", "16-45", "45+"}},
+	}
+
+	for _, tt := range tests {
+		got, err := agingBuckets(tt.limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("agingBuckets(%v) = %v, want %v", tt.limits, got, tt.want)
+		}
+	}
+
+	for _, limits := range [][]int{nil, {-1, 30}, {30, 30}, {60, 30}} {
+		if _, err := agingBuckets(limits); err == nil {
+			t.Errorf("agingBuckets(%v) expected an error", limits)
+		}
+	}
+}
+
+func TestAgingBucket(t *testing.T) {
+	tests := []struct {
+		days int
+		want int
+	}{
+		{-5, 0},
+		{0, 0},
+		{1, 1},
+		{30, 1},
+		{31, 2},
+		{60, 2},
+		{61, 3},
+		{90, 3},
+		{91, 4},
+	}
+
+	for _, tt := range tests {
+		if got := agingBucket(DefaultAgingLimits, tt.days); got != tt.want {
+			t.Errorf("agingBucket(%d) = %d, want %d", tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestAgingReport(t *testing.T) {
+	items := []agingItem{
+		{party: "C1", dueDate: "2024-04-30", outstanding: 1000},
+		{party: "C1", dueDate: "2024-03-31", outstanding: 2000},
+		{party: "C1", dueDate: "2024-03-30", outstanding: 3000},
+		{party: "C1", dueDate: "2024-01-31", outstanding: 4000},
+		{party: "C1", dueDate: "2024-01-30", outstanding: 5000},
+		{party: "C2", name: "Acme", dueDate: "2024-01-01", outstanding: -2550},
+		{party: "C2", name: "Acme", dueDate: "2024-04-01", outstanding: 0},
+		{party: "C2", name: "Acme", dueDate: "2024-05-10", outstanding: 1025},
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		got, err := agingReport("2024-04-30", DefaultAgingLimits, false, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := models.AgingReport{
+			Date:    "2024-04-30",
+			Buckets: []string{"Current", "1-30", "31-60", "61-90", "90+"},
+			Rows: []models.AgingRow{
+				{Party: "C1", Buckets: []float64{10, 20, 30, 40, 50}, Total: 150},
+				{Party: "C2", PartyName: "Acme", Buckets: []float64{10.25, 0, 0, 0, 0}, Unapplied: -25.5, Total: -15.25},
+			},
+			Totals:    []float64{20.25, 20, 30, 40, 50},
+			Unapplied: -25.5,
+			Total:     134.75,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		got, err := agingReport("2024-04-30", DefaultAgingLimits, true, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The settled item is left out
+		if len(got.Rows) != 7 {
+			t.Fatalf("got %d rows, want 7", len(got.Rows))
+		}
+		wantDays := []int{0, 30, 31, 90, 91, 120, -10}
+		for i, r := range got.Rows {
+			if r.Days != wantDays[i] {
+				t.Errorf("row %d: got %d days, want %d", i, r.Days, wantDays[i])
+			}
+		}
+		credit := got.Rows[5]
+		if credit.Unapplied != -25.5 || credit.Total != -25.5 || !reflect.DeepEqual(credit.Buckets, []float64{0, 0, 0, 0, 0}) {
+			t.Errorf("credit row: got %+v", credit)
+		}
+		if got.Total != 134.75 || got.Unapplied != -25.5 {
+			t.Errorf("got total %v and unapplied %v, want 134.75 and -25.5", got.Total, got.Unapplied)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := agingReport("2024-04-30", []int{30, 0}, false, items); err == nil {
+			t.Error("invalid limits: expected an error")
+		}
+		if _, err := agingReport("30/04/2024", DefaultAgingLimits, false, items); err == nil {
+			t.Error("invalid date: expected an error")
+		}
+		bad := []agingItem{{party: "C1", dueDate: "soon", outstanding: 100}}
+		if _, err := agingReport("2024-04-30", DefaultAgingLimits, false, bad); err == nil {
+			t.Error("invalid due date: expected an error")
+		}
+	})
+}
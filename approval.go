@@ -0,0 +1,425 @@
+package scribe
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Draft types
+const (
+	DraftJournal        = "journal"
+	DraftPaymentVoucher = "payment_voucher"
+	DraftDeposit        = "deposit"
+)
+
+var draftTypes = []string{DraftJournal, DraftPaymentVoucher, DraftDeposit}
+
+// Draft statuses. Drafts move from draft to submitted to approved to
+// posted, optionally through scheduled to be posted on their posting date.
+// Rejected drafts return to draft and discarded drafts are kept for
+// reference only.
+const (
+	DraftStatusDraft     = "draft"
+	DraftStatusSubmitted = "submitted"
+	DraftStatusApproved  = "approved"
+	DraftStatusScheduled = "scheduled"
+	DraftStatusPosted    = "posted"
+	DraftStatusDiscarded = "discarded"
+)
+
+var draftColumns = []string{"type", "posting_date", "remark", "entries", "from_account_id", "amount", "due_date", "check_number", "payee", "reverse_on"}
+
+func draftValues(d models.Draft) []interface{} {
+	return []interface{}{d.Type, d.PostingDate, d.Remark, d.Entries, d.FromAccountID, d.Amount, d.DueDate, d.CheckNumber, d.Payee, d.ReverseOn}
+}
+
+// journalTotal validates that journal entries balance and returns their
+// total debit
+func journalTotal(journalEntries []models.JournalEntry) (int64, error) {
+	var debits, credits int64
+	for _, e := range journalEntries {
+		var dr, cr int64
+		var err error
+		if e.Debit != "" {
+			if dr, err = parseAmount(e.Debit); err != nil {
+				return 0, err
+			}
+		}
+		if e.Credit != "" {
+			if cr, err = parseAmount(e.Credit); err != nil {
+				return 0, err
+			}
+		}
+		debits += dr
+		credits += cr
+	}
+	if debits == 0 || debits != credits {
+		return 0, fmt.Errorf("journal does not balance: debits %s, credits %s", formatAmount(debits), formatAmount(credits))
+	}
+	return debits, nil
+}
+
+// draftTotal validates a draft for submission and returns its total debit
+// and the accounts it posts to
+func draftTotal(d models.DraftDetails) (int64, []string, error) {
+	if err := validatePostingDate(d.PostingDate); err != nil {
+		return 0, nil, err
+	}
+
+	switch d.Type {
+	case DraftJournal:
+		var journalEntries []models.JournalEntry
+		if err := json.Unmarshal([]byte(d.Entries), &journalEntries); err != nil {
+			return 0, nil, errors.New("invalid journal entries")
+		}
+		debits, err := journalTotal(journalEntries)
+		if err != nil {
+			return 0, nil, err
+		}
+		if d.ReverseOn != "" {
+			if err := validateReversalDate(d.PostingDate, d.ReverseOn); err != nil {
+				return 0, nil, err
+			}
+		}
+		accounts := make([]string, len(journalEntries))
+		for i, e := range journalEntries {
+			accounts[i] = e.Account
+		}
+		return debits, accounts, nil
+	case DraftPaymentVoucher, DraftDeposit:
+		amount, err := parseAmount(d.Amount)
+		if err != nil {
+			return 0, nil, err
+		}
+		var paymentVoucher []models.PaymentVoucherEntry
+		if err := json.Unmarshal([]byte(d.Entries), &paymentVoucher); err != nil {
+			return 0, nil, fmt.Errorf("invalid %s entries", strings.Replace(d.Type, "_", " ", 1))
+		}
+		var debits int64
+		accounts := []string{d.FromAccountID}
+		for _, e := range paymentVoucher {
+			dr, err := parseAmount(e.Amount)
+			if err != nil {
+				return 0, nil, err
+			}
+			debits += dr
+			accounts = append(accounts, e.Account)
+		}
+		if amount == 0 || debits != amount {
+			return 0, nil, fmt.Errorf("%s entries total %s, expected %s", strings.Replace(d.Type, "_", " ", 1), formatAmount(debits), formatAmount(amount))
+		}
+		return amount, accounts, nil
+	default:
+		return 0, nil, fmt.Errorf("unknown draft type %s", d.Type)
+	}
+}
+
+// checkApprovalLimit verifies that the approver may approve the amount on
+// every account. An account specific limit takes precedence over the
+// approver's general limit.
+func checkApprovalLimit(tx *sql.Tx, approverID string, total int64, accounts []string) error {
+	var limits []models.ApprovalLimit
+	err := mysequel.QueryToStructs(&limits, tx, queries.ApprovalLimits, approverID)
+	if err != nil {
+		return err
+	}
+
+	byAccount := make(map[string]int64, len(limits))
+	for _, l := range limits {
+		byAccount[strconv.Itoa(l.AccountID)] = toCents(l.MaxAmount)
+	}
+	general, hasGeneral := byAccount["0"]
+
+	for _, a := range accounts {
+		limit, ok := byAccount[a]
+		if !ok {
+			if !hasGeneral {
+				return fmt.Errorf("no approval limit for account %s", a)
+			}
+			limit = general
+		}
+		if total > limit {
+			return fmt.Errorf("amount %s exceeds approval limit of %s for account %s", formatAmount(total), formatAmount(limit), a)
+		}
+	}
+	return nil
+}
+
+// lockDraft reads a draft for update and checks that it is in one of the
+// given statuses
+func lockDraft(tx *sql.Tx, id int64, statuses ...string) (models.DraftDetails, error) {
+	var res []models.DraftDetails
+	err := mysequel.QueryToStructs(&res, tx, queries.DraftForUpdate, id)
+	if err != nil {
+		return models.DraftDetails{}, err
+	}
+	if len(res) == 0 {
+		return models.DraftDetails{}, errors.New("draft does not exist")
+	}
+	if !containsString(statuses, res[0].Status) {
+		return models.DraftDetails{}, fmt.Errorf("draft is %s", res[0].Status)
+	}
+	return res[0], nil
+}
+
+func setDraft(tx *sql.Tx, id int64, cols []string, vals []interface{}) error {
+	_, err := mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "draft",
+			Columns:   cols,
+			Vals:      vals,
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(id, 10)},
+	})
+	return err
+}
+
+// draftTx runs fn on the draft in a database transaction after checking
+// its status
+func (m *AccountModel) draftTx(id int64, statuses []string, fn func(tx *sql.Tx, d models.DraftDetails) error) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	d, err := lockDraft(tx, id, statuses...)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx, d)
+	return err
+}
+
+// insertDraft stores a draft in the given status
+func insertDraft(tx *sql.Tx, userID, status string, d models.Draft) (int64, error) {
+	cols := append([]string{"user_id", "status", "datetime"}, draftColumns...)
+	vals := append([]interface{}{userID, status, time.Now().Format("2006-01-02 15:04:05")}, draftValues(d)...)
+	return mysequel.Insert(mysequel.Table{
+		TableName: "draft",
+		Columns:   cols,
+		Vals:      vals,
+		Tx:        tx,
+	})
+}
+
+// CreateDraft saves a journal, payment voucher or deposit as a draft
+// without affecting balances
+func (m *AccountModel) CreateDraft(userID string, d models.Draft) (int64, error) {
+	if !containsString(draftTypes, d.Type) {
+		return 0, fmt.Errorf("unknown draft type %s", d.Type)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	did, err := insertDraft(tx, userID, DraftStatusDraft, d)
+	if err != nil {
+		return 0, err
+	}
+
+	return did, nil
+}
+
+// UpdateDraft replaces the content of a draft. Only the maker can edit a
+// draft and only before it is submitted.
+func (m *AccountModel) UpdateDraft(userID string, id int64, d models.Draft) error {
+	if !containsString(draftTypes, d.Type) {
+		return fmt.Errorf("unknown draft type %s", d.Type)
+	}
+
+	return m.draftTx(id, []string{DraftStatusDraft}, func(tx *sql.Tx, cur models.DraftDetails) error {
+		if cur.UserID != userID {
+			return errors.New("only the maker can edit a draft")
+		}
+		return setDraft(tx, id, draftColumns, draftValues(d))
+	})
+}
+
+// SubmitDraft validates a draft and submits it for approval
+func (m *AccountModel) SubmitDraft(userID string, id int64) error {
+	return m.draftTx(id, []string{DraftStatusDraft}, func(tx *sql.Tx, d models.DraftDetails) error {
+		if d.UserID != userID {
+			return errors.New("only the maker can submit a draft")
+		}
+		if _, _, err := draftTotal(d); err != nil {
+			return err
+		}
+		return setDraft(tx, id, []string{"status"}, []interface{}{DraftStatusSubmitted})
+	})
+}
+
+// ApproveDraft approves a submitted draft. Makers cannot approve their own
+// drafts and the amount must be within the approver's limits for every
+// account posted to.
+func (m *AccountModel) ApproveDraft(userID string, id int64) error {
+	return m.draftTx(id, []string{DraftStatusSubmitted}, func(tx *sql.Tx, d models.DraftDetails) error {
+		if d.UserID == userID {
+			return errors.New("makers cannot approve their own drafts")
+		}
+		total, accounts, err := draftTotal(d)
+		if err != nil {
+			return err
+		}
+		if err := checkApprovalLimit(tx, userID, total, accounts); err != nil {
+			return err
+		}
+		return setDraft(tx, id, []string{"status", "approved_by"}, []interface{}{DraftStatusApproved, userID})
+	})
+}
+
+// RejectDraft returns a submitted draft to its maker for editing
+func (m *AccountModel) RejectDraft(userID string, id int64) error {
+	return m.draftTx(id, []string{DraftStatusSubmitted}, func(tx *sql.Tx, d models.DraftDetails) error {
+		if d.UserID == userID {
+			return errors.New("makers cannot reject their own drafts")
+		}
+		return setDraft(tx, id, []string{"status"}, []interface{}{DraftStatusDraft})
+	})
+}
+
+// DiscardDraft discards a draft that has not been posted
+func (m *AccountModel) DiscardDraft(userID string, id int64) error {
+	return m.draftTx(id, []string{DraftStatusDraft, DraftStatusSubmitted, DraftStatusApproved, DraftStatusScheduled}, func(tx *sql.Tx, d models.DraftDetails) error {
+		if d.UserID != userID {
+			return errors.New("only the maker can discard a draft")
+		}
+		_, err := tx.Exec(queries.DeleteDraftBankLineProposal, id)
+		if err != nil {
+			return err
+		}
+		return setDraft(tx, id, []string{"status"}, []interface{}{DraftStatusDiscarded})
+	})
+}
+
+// postDraft posts an approved draft in the name of its maker and records
+// the user who posted it. Drafts created from bank statement lines match
+// their line.
+func postDraft(tx *sql.Tx, branch, postedBy string, d models.DraftDetails) (int64, error) {
+	var tid int64
+	var err error
+	switch d.Type {
+	case DraftJournal:
+		tid, err = issueJournalEntry(tx, branch, d.UserID, d.PostingDate, d.Remark, d.Entries, d.ReverseOn)
+	case DraftPaymentVoucher:
+		tid, err = issuePaymentVoucher(tx, branch, d.UserID, d.PostingDate, d.FromAccountID, d.Amount, d.Entries, d.Remark, d.DueDate, d.CheckNumber, d.Payee)
+	case DraftDeposit:
+		tid, err = issueDeposit(tx, branch, d.UserID, d.PostingDate, d.FromAccountID, d.Amount, d.Entries, d.Remark)
+	default:
+		err = fmt.Errorf("unknown draft type %s", d.Type)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = matchDraftLine(tx, d.UserID, int64(d.ID), tid)
+	if err != nil {
+		return 0, err
+	}
+
+	err = setDraft(tx, int64(d.ID), []string{"status", "posted_by", "transaction_id"}, []interface{}{DraftStatusPosted, mysequel.NewNullString(postedBy), tid})
+	if err != nil {
+		return 0, err
+	}
+	return tid, nil
+}
+
+// PostDraft posts an approved draft and returns the transaction ID
+func (m *AccountModel) PostDraft(userID string, id int64) (int64, error) {
+	var tid int64
+	err := m.draftTx(id, []string{DraftStatusApproved}, func(tx *sql.Tx, d models.DraftDetails) error {
+		var err error
+		tid, err = postDraft(tx, m.Branch, userID, d)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// Drafts returns drafts in the given status, or all drafts when status is
+// empty
+func (m *AccountModel) Drafts(status string) ([]models.DraftDetails, error) {
+	s := mysequel.NewNullString(status)
+	var res []models.DraftDetails
+	err := mysequel.QueryToStructs(&res, m.DB, queries.Drafts, s, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// DraftsPage returns a page of drafts in the given status, or of all
+// drafts when status is empty, oldest first
+func (m *AccountModel) DraftsPage(status, cursor string, limit int) (models.DraftPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.DraftPage{}, err
+	}
+	size := pageSize(limit)
+	s := mysequel.NewNullString(status)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.DraftsCount, s, s).Scan(&c.Total)
+		if err != nil {
+			return models.DraftPage{}, err
+		}
+	}
+
+	var res []models.DraftDetails
+	err = mysequel.QueryToStructs(&res, m.DB, queries.DraftsPage, append(append([]interface{}{s, s}, c.args(1)...), size+1)...)
+	if err != nil {
+		return models.DraftPage{}, err
+	}
+
+	page := models.DraftPage{Drafts: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Drafts = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
+// DraftDetails returns a draft
+func (m *AccountModel) DraftDetails(id int64) (models.DraftDetails, error) {
+	var res []models.DraftDetails
+	err := mysequel.QueryToStructs(&res, m.DB, queries.Draft, id)
+	if err != nil {
+		return models.DraftDetails{}, err
+	}
+	if len(res) == 0 {
+		return models.DraftDetails{}, errors.New("draft does not exist")
+	}
+
+	return res[0], nil
+}
@@ -0,0 +1,82 @@
+package scribe
+
+import (
+	"fmt"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// auditFilterArgs returns the placeholder values of the audit trail
+// filters, split around the keyset condition
+func auditFilterArgs(f models.AuditFilter) ([]interface{}, []interface{}, error) {
+	round := "1000"
+	if f.RoundAmount != "" {
+		cents, err := parseAmount(f.RoundAmount)
+		if err != nil || cents == 0 {
+			return nil, nil, fmt.Errorf("round amount %q must be a positive number", f.RoundAmount)
+		}
+		round = formatAmount(cents)
+	}
+	n := mysequel.NewNullString
+	dateFrom, dateTo := n(f.DateFrom), n(f.DateTo)
+	postingFrom, postingTo := n(f.PostingDateFrom), n(f.PostingDateTo)
+	user, account := n(f.UserID), n(f.AccountID)
+	minAmount, maxAmount := n(f.MinAmount), n(f.MaxAmount)
+	remark, typ := n(f.Remark), n(f.Type)
+
+	where := []interface{}{
+		round, round,
+		dateFrom, dateFrom, dateTo, dateTo,
+		postingFrom, postingFrom, postingTo, postingTo,
+		user, user,
+		account, account,
+		minAmount, maxAmount, minAmount, maxAmount,
+		remark, remark,
+	}
+	having := []interface{}{typ, typ, f.FlaggedOnly}
+	return where, having, nil
+}
+
+// AuditTrail returns a page of journal entry lines matching the filter,
+// ordered by transaction with debits before credits. Each line carries
+// flags for weekend postings, round amounts, back-dated entries and
+// self-approved drafts.
+func (m *AccountModel) AuditTrail(filter models.AuditFilter, cursor string, limit int) (models.AuditTrailPage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.AuditTrailPage{}, err
+	}
+	size := pageSize(limit)
+
+	where, having, err := auditFilterArgs(filter)
+	if err != nil {
+		return models.AuditTrailPage{}, err
+	}
+	if c.first() {
+		err = m.DB.QueryRow(queries.AuditTrailCount, append(where, having...)...).Scan(&c.Total)
+		if err != nil {
+			return models.AuditTrailPage{}, err
+		}
+	}
+
+	args := append(append(append([]interface{}{}, where...), c.args(3)...), having...)
+	var res []models.AuditEntry
+	err = mysequel.QueryToStructs(&res, m.DB, queries.AuditTrail, append(args, size+1)...)
+	if err != nil {
+		return models.AuditTrailPage{}, err
+	}
+
+	page := models.AuditTrailPage{Entries: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Entries = res[:size]
+		last := res[size-1]
+		typeOrder := 1
+		if last.Type == "DR" {
+			typeOrder = 0
+		}
+		page.NextCursor = encodeCursor(c.Total, last.TransactionID, typeOrder, last.ID)
+	}
+	return page, nil
+}
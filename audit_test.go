@@ -0,0 +1,26 @@
+package scribe
+
+import (
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+func TestAuditTrailRoundAmount(t *testing.T) {
+	db, _ := newStubDB(t, nil)
+	m := &AccountModel{DB: db}
+
+	for _, round := range []string{"0", "0.001", "-100", "abc", "NaN"} {
+		if _, err := m.AuditTrail(models.AuditFilter{RoundAmount: round}, "", 0); err == nil {
+			t.Errorf("round amount %q: expected an error", round)
+		}
+	}
+
+	where, _, err := auditFilterArgs(models.AuditFilter{RoundAmount: "500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where[0] != "500.00" {
+		t.Errorf("got round amount %v, want 500.00", where[0])
+	}
+}
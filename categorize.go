@@ -0,0 +1,523 @@
+package scribe
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Directions of money on a bank statement line a bank rule applies to
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+type bankRule struct {
+	models.BankRule
+	pattern *regexp.Regexp
+}
+
+// compileBankRule validates a rule and compiles its description pattern,
+// which is matched case insensitively
+func compileBankRule(r models.BankRule) (bankRule, error) {
+	br := bankRule{BankRule: r}
+	if r.AccountID == 0 {
+		return br, errors.New("rule account is required")
+	}
+	if r.Direction != "" && r.Direction != DirectionIn && r.Direction != DirectionOut {
+		return br, errors.New("invalid rule direction")
+	}
+	if r.MinAmount < 0 || r.MaxAmount < 0 || (r.MaxAmount > 0 && r.MaxAmount < r.MinAmount) {
+		return br, errors.New("invalid rule amount range")
+	}
+	if r.Pattern != "" {
+		p, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return br, errors.New("invalid rule pattern")
+		}
+		br.pattern = p
+	}
+	return br, nil
+}
+
+// matches reports whether the rule applies to a line on a statement of
+// the bank account
+func (r bankRule) matches(bankAccountID int, l models.BankStatementLine) bool {
+	if r.BankAccountID != 0 && r.BankAccountID != bankAccountID {
+		return false
+	}
+	if r.Direction == DirectionIn && l.Amount <= 0 || r.Direction == DirectionOut && l.Amount >= 0 {
+		return false
+	}
+	amount := absCents(toCents(l.Amount))
+	if amount < toCents(r.MinAmount) || (r.MaxAmount > 0 && amount > toCents(r.MaxAmount)) {
+		return false
+	}
+	if r.Counterparty != "" && !strings.Contains(strings.ToLower(l.Counterparty), strings.ToLower(r.Counterparty)) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(l.Description) {
+		return false
+	}
+	return true
+}
+
+// CreateBankRule creates a rule categorizing imported bank statement lines
+func (m *AccountModel) CreateBankRule(userID string, r models.BankRule) (int64, error) {
+	if strings.TrimSpace(r.Name) == "" {
+		return 0, errors.New("rule name is required")
+	}
+	if _, err := compileBankRule(r); err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	// Blank bounds and bank account are stored as NULL
+	bound := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return formatAmount(toCents(v))
+	}
+	bankAccountID := ""
+	if r.BankAccountID != 0 {
+		bankAccountID = strconv.Itoa(r.BankAccountID)
+	}
+	autoPost := 0
+	if r.AutoPost {
+		autoPost = 1
+	}
+
+	br := mysequel.Table{
+		TableName: "bank_rule",
+		Columns: []string{"name", "priority", "bank_account_id", "pattern", "counterparty", "direction", "min_amount", "max_amount",
+			"account_id", "auto_post", "active", "user_id", "datetime"},
+		Vals: []interface{}{r.Name, r.Priority, bankAccountID, r.Pattern, r.Counterparty, r.Direction, bound(r.MinAmount),
+			bound(r.MaxAmount), r.AccountID, autoPost, 1, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx: tx,
+	}
+	rid, err := mysequel.Insert(br)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, br.TableName, rid, br.Columns, br.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+// SetBankRuleActive enables or disables a bank rule
+func (m *AccountModel) SetBankRuleActive(userID string, ruleID int64, active bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	flag := 0
+	if active {
+		flag = 1
+	}
+	err = updateRecord(tx, userID, "bank_rule", ruleID, []string{"active"}, []interface{}{flag})
+	return err
+}
+
+// BankRules returns all bank rules in the order they are tried
+func (m *AccountModel) BankRules() ([]models.BankRule, error) {
+	var res []models.BankRule
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BankRules, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// statementLineDraft is the deposit for money in or the payment voucher
+// for money out that records a statement line against an account
+func statementLineDraft(bankAccountID int, l models.BankStatementLine, accountID int) (models.Draft, error) {
+	amount := formatAmount(absCents(toCents(l.Amount)))
+	entries, err := json.Marshal([]models.PaymentVoucherEntry{{Account: strconv.Itoa(accountID), Amount: amount}})
+	if err != nil {
+		return models.Draft{}, err
+	}
+	d := models.Draft{Type: DraftDeposit, PostingDate: l.Date, Remark: l.Description, Entries: string(entries),
+		FromAccountID: strconv.Itoa(bankAccountID), Amount: amount}
+	if d.Remark == "" {
+		d.Remark = l.Counterparty
+	}
+	if l.Amount < 0 {
+		d.Type, d.Payee = DraftPaymentVoucher, l.Counterparty
+	}
+	return d, nil
+}
+
+// matchPostedLine matches a statement line with the bank entry of the
+// transaction posted for it and clears its proposal
+func matchPostedLine(tx *sql.Tx, userID string, bankAccountID int, l models.BankStatementLine, tid int64) error {
+	var entryID int
+	err := tx.QueryRow(queries.TransactionAccountEntry, tid, bankAccountID).Scan(&entryID)
+	if err != nil {
+		return err
+	}
+
+	_, err = insertMatch(tx, userID, MatchRule, []int{l.ID}, []int{entryID})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(queries.DeleteBankLineProposal, l.ID)
+	return err
+}
+
+// postStatementLine posts a statement line against an account with a
+// deposit for money in or a payment voucher for money out, and matches
+// the line with the bank entry of the posting
+func postStatementLine(tx *sql.Tx, branch, userID string, s statementSession, l models.BankStatementLine, accountID int) (int64, error) {
+	if l.MatchID != 0 {
+		return 0, errors.New("statement line is already matched")
+	}
+
+	d, err := statementLineDraft(s.accountID, l, accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	var tid int64
+	if d.Type == DraftPaymentVoucher {
+		tid, err = issuePaymentVoucher(tx, branch, userID, d.PostingDate, d.FromAccountID, d.Amount, d.Entries, d.Remark, "", "", d.Payee)
+	} else {
+		tid, err = issueDeposit(tx, branch, userID, d.PostingDate, d.FromAccountID, d.Amount, d.Entries, d.Remark)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = matchPostedLine(tx, userID, s.accountID, l, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// draftStatementLine submits the posting of a statement line for approval.
+// The line leaves the review queue and is matched when the draft is
+// posted.
+func draftStatementLine(tx *sql.Tx, userID string, s statementSession, l models.BankStatementLine, ruleID, accountID int) (int64, error) {
+	if l.MatchID != 0 {
+		return 0, errors.New("statement line is already matched")
+	}
+
+	d, err := statementLineDraft(s.accountID, l, accountID)
+	if err != nil {
+		return 0, err
+	}
+	did, err := insertDraft(tx, userID, DraftStatusSubmitted, d)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(queries.DeleteBankLineProposal, l.ID)
+	if err != nil {
+		return 0, err
+	}
+	rule := ""
+	if ruleID != 0 {
+		rule = strconv.Itoa(ruleID)
+	}
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "bank_line_proposal",
+		Columns:   []string{"bank_statement_line_id", "bank_rule_id", "account_id", "status", "draft_id", "datetime"},
+		Vals:      []interface{}{l.ID, rule, accountID, "drafted", did, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return did, nil
+}
+
+// matchDraftLine matches the statement line a posted draft was created
+// for, if any. The line must still be unmatched on an open statement and
+// agree with the amount posted.
+func matchDraftLine(tx *sql.Tx, userID string, draftID, tid int64) error {
+	var lineID, statementID int64
+	err := tx.QueryRow(queries.DraftBankLine, draftID).Scan(&lineID, &statementID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := lockStatement(tx, statementID)
+	if err != nil {
+		return err
+	}
+	var lines []models.BankStatementLine
+	err = mysequel.QueryToStructs(&lines, tx, queries.StatementLineForUpdate, statementID, lineID)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return errors.New("statement line not found")
+	}
+	l := lines[0]
+	if l.MatchID != 0 {
+		return errors.New("statement line is already matched")
+	}
+
+	var entries []models.BookEntry
+	err = mysequel.QueryToStructs(&entries, tx, queries.TransactionBookEntries, tid, s.accountID)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += toCents(e.Amount)
+	}
+	if total != toCents(l.Amount) {
+		return errors.New("draft amount differs from the statement line")
+	}
+
+	return matchPostedLine(tx, userID, s.accountID, l, tid)
+}
+
+// CategorizeStatement applies the active bank rules to the unmatched lines
+// of a statement that have not been categorized yet. Lines matching an
+// auto posting rule are posted and matched, each in its own database
+// transaction. Other matching lines are proposed for review, as are all
+// matching lines when postings require approval.
+func (m *AccountModel) CategorizeStatement(userID string, statementID int64) (models.CategorizeResult, error) {
+	res := models.CategorizeResult{Posted: []models.CategorizedLine{}, Proposed: []models.CategorizedLine{}, Failed: []models.CategorizedLine{}}
+
+	var s statementSession
+	var status string
+	err := m.DB.QueryRow(queries.BankStatement, statementID).Scan(&s.accountID, &s.startDate, &s.endDate, &s.closingBalance, &status)
+	if err != nil {
+		return res, err
+	}
+	if status != StatementOpen {
+		return res, errors.New("reconciliation is closed")
+	}
+
+	var stored []models.BankRule
+	err = mysequel.QueryToStructs(&stored, m.DB, queries.BankRules, 1, 1)
+	if err != nil {
+		return res, err
+	}
+	rules := make([]bankRule, 0, len(stored))
+	for _, r := range stored {
+		br, err := compileBankRule(r)
+		if err != nil {
+			return res, err
+		}
+		rules = append(rules, br)
+	}
+
+	var lines []models.BankStatementLine
+	err = mysequel.QueryToStructs(&lines, m.DB, queries.UncategorizedStatementLines, statementID)
+	if err != nil {
+		return res, err
+	}
+
+	for _, l := range lines {
+		var rule *bankRule
+		for i := range rules {
+			if rules[i].matches(s.accountID, l) {
+				rule = &rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			res.Unmatched++
+			continue
+		}
+
+		c := models.CategorizedLine{LineID: l.ID, RuleID: rule.ID, AccountID: rule.AccountID}
+		if rule.AutoPost && !m.RequireApproval {
+			err = m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+				var err error
+				c.TransactionID, err = postStatementLine(tx, m.Branch, userID, s, l, rule.AccountID)
+				return err
+			})
+			if err != nil {
+				c.Error = err.Error()
+				res.Failed = append(res.Failed, c)
+				continue
+			}
+			res.Posted = append(res.Posted, c)
+			continue
+		}
+
+		err = m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+			_, err := mysequel.Insert(mysequel.Table{
+				TableName: "bank_line_proposal",
+				Columns:   []string{"bank_statement_line_id", "bank_rule_id", "account_id", "status", "datetime"},
+				Vals:      []interface{}{l.ID, rule.ID, rule.AccountID, "proposed", time.Now().Format("2006-01-02 15:04:05")},
+				Tx:        tx,
+			})
+			return err
+		})
+		if err != nil {
+			c.Error = err.Error()
+			res.Failed = append(res.Failed, c)
+			continue
+		}
+		res.Proposed = append(res.Proposed, c)
+	}
+
+	return res, nil
+}
+
+// BankReviewQueue returns the unmatched lines of a statement with the
+// accounts proposed for them. Lines without a proposal need to be posted
+// or matched by hand.
+func (m *AccountModel) BankReviewQueue(statementID int64) ([]models.BankReviewItem, error) {
+	var res []models.BankReviewItem
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BankReviewQueue, statementID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// BankReviewQueuePage returns a page of BankReviewQueue
+func (m *AccountModel) BankReviewQueuePage(statementID int64, cursor string, limit int) (models.BankReviewPage, error) {
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.BankReviewPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.BankReviewQueueCount, statementID).Scan(&c.Total)
+		if err != nil {
+			return models.BankReviewPage{}, err
+		}
+	}
+
+	var res []models.BankReviewItem
+	args := append([]interface{}{statementID}, c.args(2)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.BankReviewQueuePage, append(args, size+1)...)
+	if err != nil {
+		return models.BankReviewPage{}, err
+	}
+
+	page := models.BankReviewPage{Items: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Items = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.Date, last.LineID)
+	}
+	return page, nil
+}
+
+// AcceptBankLine posts a statement line from the review queue and matches
+// it. The proposed account is used when accountID is zero. When postings
+// require approval a submitted draft is created instead and the line is
+// matched once the draft is posted.
+func (m *AccountModel) AcceptBankLine(userID string, lineID int64, accountID int) (models.CategorizedLine, error) {
+	c := models.CategorizedLine{LineID: int(lineID), AccountID: accountID}
+
+	var statementID int64
+	err := m.DB.QueryRow(queries.StatementLineStatement, lineID).Scan(&statementID)
+	if err != nil {
+		return c, err
+	}
+
+	err = m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+		var lines []models.BankStatementLine
+		err := mysequel.QueryToStructs(&lines, tx, queries.StatementLineForUpdate, statementID, lineID)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return errors.New("statement line not found")
+		}
+
+		var drafts int
+		err = tx.QueryRow(queries.BankLineDraftCount, lineID).Scan(&drafts)
+		if err != nil {
+			return err
+		}
+		if drafts > 0 {
+			return errors.New("statement line is awaiting approval")
+		}
+
+		var ruleID, proposed int
+		err = tx.QueryRow(queries.BankLineProposal, lineID).Scan(&ruleID, &proposed)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if c.AccountID == 0 {
+			if err == sql.ErrNoRows {
+				return errors.New("statement line has no proposed account")
+			}
+			c.AccountID = proposed
+		}
+		if c.AccountID == proposed {
+			c.RuleID = ruleID
+		}
+
+		if m.RequireApproval {
+			c.DraftID, err = draftStatementLine(tx, userID, s, lines[0], c.RuleID, c.AccountID)
+			return err
+		}
+		c.TransactionID, err = postStatementLine(tx, m.Branch, userID, s, lines[0], c.AccountID)
+		return err
+	})
+	if err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// RejectBankLine rejects the account proposed for a statement line. The
+// line stays in the review queue and is not proposed again.
+func (m *AccountModel) RejectBankLine(lineID int64) error {
+	res, err := m.DB.Exec(queries.RejectBankLineProposal, lineID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("statement line has no proposed account")
+	}
+	return nil
+}
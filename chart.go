@@ -0,0 +1,507 @@
+package scribe
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// ChartOfAccounts returns chart of accounts
+func (m *AccountModel) ChartOfAccounts() ([]models.ChartOfAccount, error) {
+	var res []models.ChartOfAccount
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ChartOfAccounts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ChartOfAccountsTree returns the chart of accounts as a tree with
+// balances as at the posting date rolled up to every node
+func (m *AccountModel) ChartOfAccountsTree(postingDate string) ([]*models.ChartNode, error) {
+	var rows []models.ChartNodeRow
+	err := mysequel.QueryToStructs(&rows, m.DB, queries.ChartTree, postingDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildChartTree(rows)
+}
+
+func chartNodeKey(level string, id int) string {
+	return fmt.Sprintf("%s:%d", level, id)
+}
+
+// buildChartTree links the nodes to their parents and rolls up balances
+func buildChartTree(rows []models.ChartNodeRow) ([]*models.ChartNode, error) {
+	nodes := make(map[string]*models.ChartNode, len(rows))
+	var roots []*models.ChartNode
+	var pending []*models.ChartNode
+
+	for _, r := range rows {
+		n := &models.ChartNode{
+			Level:       r.Level,
+			ID:          r.ID,
+			ParentLevel: r.ParentLevel,
+			ParentID:    r.ParentID,
+			AccountID:   r.AccountID,
+			Name:        r.Name,
+			Balance:     r.Balance,
+			Children:    []*models.ChartNode{},
+		}
+		nodes[chartNodeKey(r.Level, r.ID)] = n
+		if r.ParentLevel == "" {
+			roots = append(roots, n)
+			continue
+		}
+		pending = append(pending, n)
+	}
+
+	// Nested categories can reference a parent that sorts after them, so
+	// parents are resolved only once every node is known
+	for _, n := range pending {
+		parent, ok := nodes[chartNodeKey(n.ParentLevel, n.ParentID)]
+		if !ok {
+			return nil, fmt.Errorf("%s %d references missing parent %s %d", n.Level, n.ID, n.ParentLevel, n.ParentID)
+		}
+		parent.Children = append(parent.Children, n)
+	}
+
+	var rolled int
+	var rollUp func(n *models.ChartNode) float64
+	rollUp = func(n *models.ChartNode) float64 {
+		rolled++
+		for _, c := range n.Children {
+			n.Balance += rollUp(c)
+		}
+		return n.Balance
+	}
+	for _, n := range roots {
+		rollUp(n)
+	}
+
+	// Categories whose parent chain loops back on itself never reach a root
+	if rolled != len(nodes) {
+		return nil, errors.New("chart of accounts contains a category cycle")
+	}
+
+	return roots, nil
+}
+
+// Chart of accounts node levels
+const (
+	LevelMainAccount     = "main_account"
+	LevelSubAccount      = "sub_account"
+	LevelAccountCategory = "account_category"
+	LevelAccount         = "account"
+)
+
+// Chart import and export formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// chartParentLevels lists the levels a node of each level may be placed under
+var chartParentLevels = map[string][]string{
+	LevelMainAccount:     nil,
+	LevelSubAccount:      {LevelMainAccount},
+	LevelAccountCategory: {LevelSubAccount, LevelAccountCategory},
+	LevelAccount:         {LevelAccountCategory},
+}
+
+var chartColumns = []string{"level", "account_id", "name", "parent_level", "parent_account_id"}
+
+//go:embed templates/*.csv
+var chartTemplates embed.FS
+
+// ChartTemplates returns the names of the bundled chart of accounts templates
+func ChartTemplates() []string {
+	entries, _ := chartTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".csv"))
+	}
+	return names
+}
+
+// ChartTemplate returns the lines of a bundled chart of accounts template,
+// ready to be passed to ImportChart
+func ChartTemplate(name string) ([]models.ChartLine, error) {
+	f, err := chartTemplates.Open(path.Join("templates", name+".csv"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown chart template %s", name)
+	}
+	defer f.Close()
+
+	return ParseChart(f, FormatCSV)
+}
+
+// ParseChart reads chart of accounts lines in CSV or JSON format
+func ParseChart(r io.Reader, format string) ([]models.ChartLine, error) {
+	switch format {
+	case FormatCSV:
+		records, err := readCSV(r, chartColumns[:3])
+		if err != nil {
+			return nil, err
+		}
+		lines := make([]models.ChartLine, len(records))
+		for i, rec := range records {
+			lines[i] = models.ChartLine{
+				Level:           rec.fields["level"],
+				AccountID:       rec.fields["account_id"],
+				Name:            rec.fields["name"],
+				ParentLevel:     rec.fields["parent_level"],
+				ParentAccountID: rec.fields["parent_account_id"],
+			}
+		}
+		return lines, nil
+	case FormatJSON:
+		var lines []models.ChartLine
+		if err := json.NewDecoder(r).Decode(&lines); err != nil {
+			return nil, err
+		}
+		return lines, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// ExportChart writes the full chart of accounts in CSV or JSON format
+func (m *AccountModel) ExportChart(w io.Writer, format string) error {
+	var rows []models.ChartLineRow
+	err := mysequel.QueryToStructs(&rows, m.DB, queries.ChartLines)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]models.ChartLine, len(rows))
+	for i, r := range rows {
+		lines[i] = models.ChartLine{Level: r.Level, AccountID: r.AccountID, Name: r.Name, ParentLevel: r.ParentLevel, ParentAccountID: r.ParentAccountID}
+	}
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(chartColumns)
+		for _, l := range lines {
+			_ = cw.Write([]string{l.Level, l.AccountID, l.Name, l.ParentLevel, l.ParentAccountID})
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(lines)
+	default:
+		return fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// ImportChart compares the lines against the existing chart of accounts
+// and returns the differences. Unless dryRun is set, new nodes are created
+// and renamed or moved nodes are updated in a single transaction. Invalid
+// lines are reported together as an ImportError.
+func (m *AccountModel) ImportChart(lines []models.ChartLine, dryRun bool) ([]models.ChartChange, error) {
+	return m.ImportChartAs("", lines, dryRun)
+}
+
+// ImportChartAs imports the chart of accounts as ImportChart does with the
+// changes recorded in the change history against the user
+func (m *AccountModel) ImportChartAs(userID string, lines []models.ChartLine, dryRun bool) ([]models.ChartChange, error) {
+	if dryRun {
+		var existing []models.ChartLineRow
+		err := mysequel.QueryToStructs(&existing, m.DB, queries.ChartLines)
+		if err != nil {
+			return nil, err
+		}
+		return diffChart(existing, lines)
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var existing []models.ChartLineRow
+	err = mysequel.QueryToStructs(&existing, tx, queries.ChartLines)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffChart(existing, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyChartChanges(tx, userID, existing, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func chartKey(level, accountID string) string {
+	return level + ":" + accountID
+}
+
+func diffChart(existing []models.ChartLineRow, lines []models.ChartLine) ([]models.ChartChange, error) {
+	current := make(map[string]models.ChartLineRow, len(existing))
+	for _, e := range existing {
+		current[chartKey(e.Level, e.AccountID)] = e
+	}
+
+	var ierr ImportError
+	rows := make(map[string]int, len(lines))
+	for i, l := range lines {
+		row := i + 1
+		allowed, ok := chartParentLevels[l.Level]
+		if !ok {
+			ierr.add(row, "unknown level %q", l.Level)
+			continue
+		}
+		if l.AccountID == "" {
+			ierr.add(row, "account_id is required")
+		}
+		if l.Name == "" {
+			ierr.add(row, "name is required")
+		}
+		if len(allowed) == 0 {
+			if l.ParentLevel != "" || l.ParentAccountID != "" {
+				ierr.add(row, "%s cannot have a parent", l.Level)
+			}
+		} else if !containsString(allowed, l.ParentLevel) {
+			ierr.add(row, "parent of %s must be %s", l.Level, strings.Join(allowed, " or "))
+		} else if l.ParentAccountID == "" {
+			ierr.add(row, "parent_account_id is required")
+		}
+
+		k := chartKey(l.Level, l.AccountID)
+		if prev, dup := rows[k]; dup {
+			ierr.add(row, "duplicate of row %d", prev)
+			continue
+		}
+		rows[k] = row
+	}
+
+	// Final category parents, used to reject cycles
+	categoryParents := make(map[string]string)
+	for _, e := range existing {
+		if e.Level == LevelAccountCategory && e.ParentLevel == LevelAccountCategory {
+			categoryParents[chartKey(e.Level, e.AccountID)] = chartKey(e.ParentLevel, e.ParentAccountID)
+		}
+	}
+	for _, l := range lines {
+		if l.Level != LevelAccountCategory {
+			continue
+		}
+		k := chartKey(l.Level, l.AccountID)
+		delete(categoryParents, k)
+		if l.ParentLevel == LevelAccountCategory {
+			categoryParents[k] = chartKey(l.ParentLevel, l.ParentAccountID)
+		}
+	}
+
+	for i, l := range lines {
+		if l.ParentLevel == "" || l.ParentAccountID == "" {
+			continue
+		}
+		pk := chartKey(l.ParentLevel, l.ParentAccountID)
+		_, inFile := rows[pk]
+		_, inDB := current[pk]
+		if !inFile && !inDB {
+			ierr.add(i+1, "parent %s %s does not exist", l.ParentLevel, l.ParentAccountID)
+			continue
+		}
+		if l.Level == LevelAccountCategory {
+			k := chartKey(l.Level, l.AccountID)
+			for p, n := categoryParents[k], 0; p != "" && n <= len(categoryParents); p, n = categoryParents[p], n+1 {
+				if p == k {
+					ierr.add(i+1, "category %s is its own ancestor", l.AccountID)
+					break
+				}
+			}
+		}
+	}
+	if err := ierr.err(); err != nil {
+		return nil, err
+	}
+
+	var creates, updates, missing []models.ChartChange
+	for _, l := range lines {
+		e, ok := current[chartKey(l.Level, l.AccountID)]
+		if !ok {
+			creates = append(creates, models.ChartChange{Action: "create", Level: l.Level, AccountID: l.AccountID, Name: l.Name, ParentLevel: l.ParentLevel, ParentAccountID: l.ParentAccountID})
+			continue
+		}
+		if e.Name != l.Name || e.ParentLevel != l.ParentLevel || e.ParentAccountID != l.ParentAccountID {
+			updates = append(updates, models.ChartChange{Action: "update", Level: l.Level, AccountID: l.AccountID, Name: l.Name, ParentLevel: l.ParentLevel, ParentAccountID: l.ParentAccountID,
+				OldName: e.Name, OldParentLevel: e.ParentLevel, OldParentAccountID: e.ParentAccountID})
+		}
+	}
+	for _, e := range existing {
+		if _, ok := rows[chartKey(e.Level, e.AccountID)]; !ok {
+			missing = append(missing, models.ChartChange{Action: "missing", Level: e.Level, AccountID: e.AccountID, Name: e.Name, ParentLevel: e.ParentLevel, ParentAccountID: e.ParentAccountID})
+		}
+	}
+
+	// Order creations so that parents are always created before children
+	placed := make(map[string]bool, len(current)+len(creates))
+	for k := range current {
+		placed[k] = true
+	}
+	ordered := make([]models.ChartChange, 0, len(creates))
+	for len(ordered) < len(creates) {
+		progress := false
+		for _, c := range creates {
+			k := chartKey(c.Level, c.AccountID)
+			if placed[k] || (c.ParentLevel != "" && !placed[chartKey(c.ParentLevel, c.ParentAccountID)]) {
+				continue
+			}
+			ordered = append(ordered, c)
+			placed[k] = true
+			progress = true
+		}
+		if !progress {
+			return nil, errors.New("chart contains unresolvable parents")
+		}
+	}
+
+	changes := append(ordered, updates...)
+	return append(changes, missing...), nil
+}
+
+func applyChartChanges(tx *sql.Tx, userID string, existing []models.ChartLineRow, changes []models.ChartChange) error {
+	ids := make(map[string]int64, len(existing))
+	subs := make(map[string]int64)
+	parents := make(map[string]string)
+	for _, e := range existing {
+		k := chartKey(e.Level, e.AccountID)
+		ids[k] = int64(e.ID)
+		if e.Level == LevelAccountCategory {
+			subs[k] = int64(e.SubAccountID)
+			parents[k] = chartKey(e.ParentLevel, e.ParentAccountID)
+		}
+	}
+	for _, c := range changes {
+		if c.Level == LevelAccountCategory && c.Action != "missing" {
+			parents[chartKey(c.Level, c.AccountID)] = chartKey(c.ParentLevel, c.ParentAccountID)
+		}
+	}
+
+	// subAccountOf walks up nested categories to the owning sub account
+	subAccountOf := func(k string) int64 {
+		for p := parents[k]; p != ""; p = parents[p] {
+			if strings.HasPrefix(p, LevelSubAccount+":") {
+				return ids[p]
+			}
+		}
+		return 0
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	for _, c := range changes {
+		k := chartKey(c.Level, c.AccountID)
+		parentID := ids[chartKey(c.ParentLevel, c.ParentAccountID)]
+
+		var table string
+		var cols []string
+		var vals []interface{}
+		switch c.Level {
+		case LevelMainAccount:
+			table = "main_account"
+			cols = []string{"account_id", "name"}
+			vals = []interface{}{c.AccountID, c.Name}
+		case LevelSubAccount:
+			table = "sub_account"
+			cols = []string{"main_account_id", "account_id", "name"}
+			vals = []interface{}{parentID, c.AccountID, c.Name}
+		case LevelAccountCategory:
+			categoryParent := ""
+			if c.ParentLevel == LevelAccountCategory {
+				categoryParent = strconv.FormatInt(parentID, 10)
+			}
+			subs[k] = subAccountOf(k)
+			table = "account_category"
+			cols = []string{"sub_account_id", "parent_id", "account_id", "name"}
+			vals = []interface{}{subs[k], categoryParent, c.AccountID, c.Name}
+		case LevelAccount:
+			table = "account"
+			cols = []string{"account_category_id", "account_id", "name"}
+			vals = []interface{}{parentID, c.AccountID, c.Name}
+		}
+
+		switch c.Action {
+		case "create":
+			if c.Level == LevelAccountCategory || c.Level == LevelAccount {
+				cols = append(cols, "datetime")
+				vals = append(vals, now)
+			}
+			id, err := mysequel.Insert(mysequel.Table{
+				TableName: table,
+				Columns:   cols,
+				Vals:      vals,
+				Tx:        tx,
+			})
+			if err != nil {
+				return err
+			}
+			ids[k] = id
+
+			err = recordCreate(tx, userID, table, id, cols, vals)
+			if err != nil {
+				return err
+			}
+		case "update":
+			err := updateRecord(tx, userID, table, ids[k], cols, vals)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Categories nested under a moved category follow it to its sub account
+	for _, e := range existing {
+		k := chartKey(e.Level, e.AccountID)
+		if e.Level != LevelAccountCategory || subs[k] == subAccountOf(k) {
+			continue
+		}
+		subs[k] = subAccountOf(k)
+		err := updateRecord(tx, userID, "account_category", ids[k], []string{"sub_account_id"}, []interface{}{subs[k]})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,466 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Cheque states
+const (
+	ChequeIssued    = "issued"
+	ChequePresented = "presented"
+	ChequeCleared   = "cleared"
+	ChequeStopped   = "stopped"
+	ChequeStale     = "stale"
+	ChequeCancelled = "cancelled"
+)
+
+// StaleChequeMonths is the age after which an uncleared cheque can no
+// longer be presented
+const StaleChequeMonths = 6
+
+// chequeTransitions lists the states each cheque state can move to
+var chequeTransitions = map[string][]string{
+	ChequeIssued:    {ChequePresented, ChequeStopped, ChequeStale, ChequeCancelled},
+	ChequePresented: {ChequeCleared, ChequeStopped},
+}
+
+// chequeReversed lists the states in which the payment is reversed
+var chequeReversed = map[string]bool{ChequeStopped: true, ChequeStale: true, ChequeCancelled: true}
+
+// CreateChequeBook registers a range of cheque numbers for a bank account.
+// Ranges of the same account may not overlap.
+func (m *AccountModel) CreateChequeBook(userID string, accountID, firstNumber, lastNumber int) (int64, error) {
+	if firstNumber <= 0 || lastNumber < firstNumber {
+		return 0, errors.New("invalid cheque number range")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var overlaps int
+	err = tx.QueryRow(queries.ChequeBookOverlaps, accountID, lastNumber, firstNumber).Scan(&overlaps)
+	if err != nil {
+		return 0, err
+	}
+	if overlaps > 0 {
+		err = errors.New("cheque numbers overlap an existing cheque book")
+		return 0, err
+	}
+
+	cb := mysequel.Table{
+		TableName: "cheque_book",
+		Columns:   []string{"account_id", "first_number", "last_number", "active", "user_id", "datetime"},
+		Vals:      []interface{}{accountID, firstNumber, lastNumber, 1, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	}
+	cid, err := mysequel.Insert(cb)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, cb.TableName, cid, cb.Columns, cb.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return cid, nil
+}
+
+// CloseChequeBook stops further cheques being issued from a book
+func (m *AccountModel) CloseChequeBook(userID string, id int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	err = updateRecord(tx, userID, "cheque_book", id, []string{"active"}, []interface{}{0})
+	return err
+}
+
+// ChequeBooks returns the cheque books of an account, or of all accounts
+// when accountID is empty
+func (m *AccountModel) ChequeBooks(accountID string) ([]models.ChequeBook, error) {
+	aid := mysequel.NewNullString(accountID)
+
+	var res []models.ChequeBook
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ChequeBooks, aid, aid)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ChequeGaps returns the numbers of a cheque book that were skipped, that
+// is unused numbers below the highest number used
+func (m *AccountModel) ChequeGaps(bookID int64) ([]int, error) {
+	var first, last int
+	err := m.DB.QueryRow(queries.ChequeBookRange, bookID).Scan(&first, &last)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.Query(queries.ChequeBookNumbers, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gaps := []int{}
+	next := first
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		for ; next < n; next++ {
+			gaps = append(gaps, next)
+		}
+		next = n + 1
+	}
+	return gaps, rows.Err()
+}
+
+// chequeBook returns the active cheque book of an account containing the
+// number. Accounts without cheque books do not use the register and
+// return zero.
+func chequeBook(tx *sql.Tx, accountID string, number int) (int64, error) {
+	var books int
+	err := tx.QueryRow(queries.ChequeBookCount, accountID).Scan(&books)
+	if err != nil {
+		return 0, err
+	}
+	if books == 0 {
+		return 0, nil
+	}
+
+	var bid int64
+	err = tx.QueryRow(queries.ChequeBookForNumber, accountID, number).Scan(&bid)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("cheque %d is not in an active cheque book of the account", number)
+	} else if err != nil {
+		return 0, err
+	}
+
+	var used int
+	err = tx.QueryRow(queries.ChequeUsed, accountID, number).Scan(&used)
+	if err != nil {
+		return 0, err
+	}
+	if used > 0 {
+		return 0, fmt.Errorf("cheque %d has already been used", number)
+	}
+
+	return bid, nil
+}
+
+// registerCheque records the cheque of a payment voucher drawn on an
+// account with cheque books
+func registerCheque(tx *sql.Tx, userID, accountID, checkNumber string, pid, tid int64, amount, postingDate string) error {
+	if checkNumber == "" {
+		return nil
+	}
+
+	number, err := strconv.Atoi(checkNumber)
+	if err != nil {
+		var books int
+		if err := tx.QueryRow(queries.ChequeBookCount, accountID).Scan(&books); err != nil {
+			return err
+		}
+		if books == 0 {
+			return nil
+		}
+		return fmt.Errorf("invalid cheque number %s", checkNumber)
+	}
+
+	bid, err := chequeBook(tx, accountID, number)
+	if err != nil || bid == 0 {
+		return err
+	}
+
+	c := mysequel.Table{
+		TableName: "cheque",
+		Columns:   []string{"cheque_book_id", "account_id", "number", "payment_voucher_id", "transaction_id", "amount", "status", "status_date"},
+		Vals:      []interface{}{bid, accountID, number, pid, tid, amount, ChequeIssued, postingDate},
+		Tx:        tx,
+	}
+	cid, err := mysequel.Insert(c)
+	if err != nil {
+		return err
+	}
+
+	return recordCreate(tx, userID, c.TableName, cid, c.Columns, c.Vals)
+}
+
+// CancelChequeLeaf cancels an unused cheque leaf, such as a spoilt cheque,
+// so that it is not reported as skipped
+func (m *AccountModel) CancelChequeLeaf(userID, accountID string, number int, date string) (int64, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return 0, errors.New("invalid date")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	bid, err := chequeBook(tx, accountID, number)
+	if err != nil {
+		return 0, err
+	}
+	if bid == 0 {
+		err = errors.New("account has no cheque books")
+		return 0, err
+	}
+
+	c := mysequel.Table{
+		TableName: "cheque",
+		Columns:   []string{"cheque_book_id", "account_id", "number", "status", "status_date"},
+		Vals:      []interface{}{bid, accountID, number, ChequeCancelled, date},
+		Tx:        tx,
+	}
+	cid, err := mysequel.Insert(c)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, c.TableName, cid, c.Columns, c.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return cid, nil
+}
+
+// SetChequeStatus moves a cheque to a new state on the given date.
+// Stopping, cancelling or marking a cheque stale reverses the payment
+// voucher on that date and returns the reversing transaction ID, so these
+// states cannot be set when postings require approval.
+func (m *AccountModel) SetChequeStatus(userID string, id int64, status, date string) (int64, error) {
+	if m.RequireApproval && chequeReversed[status] {
+		return 0, ErrApprovalRequired
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	rid, err := setChequeStatus(tx, m.Branch, userID, id, status, date)
+	if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+func setChequeStatus(tx *sql.Tx, branch, userID string, id int64, status, date string) (int64, error) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, errors.New("invalid date")
+	}
+
+	var cheques []models.Cheque
+	err = mysequel.QueryToStructs(&cheques, tx, queries.ChequeForUpdate, id)
+	if err != nil {
+		return 0, err
+	}
+	if len(cheques) == 0 {
+		return 0, sql.ErrNoRows
+	}
+	c := cheques[0]
+
+	if !containsString(chequeTransitions[c.Status], status) {
+		return 0, fmt.Errorf("cheque %d cannot move from %s to %s", c.Number, c.Status, status)
+	}
+	if p, err := time.Parse("2006-01-02", c.PostingDate); err == nil && d.Before(p) {
+		return 0, errors.New("date is before the cheque was issued")
+	}
+
+	cols := []string{"status", "status_date"}
+	vals := []interface{}{status, date}
+
+	var rid int64
+	if chequeReversed[status] {
+		entries, err := transactionEntries(tx, int64(c.TransactionID))
+		if err != nil {
+			return 0, err
+		}
+		rid, err = issueReversal(tx, branch, userID, int64(c.TransactionID), date, fmt.Sprintf("cheque %d %s", c.Number, status), entries)
+		if err != nil {
+			return 0, err
+		}
+		cols = append(cols, "reversal_transaction_id")
+		vals = append(vals, rid)
+	}
+
+	err = updateRecord(tx, userID, "cheque", id, cols, vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+// MarkStaleCheques marks issued cheques stale once StaleChequeMonths have
+// passed since their due date, or their posting date when they are not
+// post-dated, and reverses their payments. Each cheque is handled in its
+// own database transaction.
+func (m *AccountModel) MarkStaleCheques(userID string, now time.Time) ([]int64, error) {
+	if m.RequireApproval {
+		return nil, ErrApprovalRequired
+	}
+
+	cutoff := now.AddDate(0, -StaleChequeMonths, 0).Format("2006-01-02")
+
+	rows, err := m.DB.Query(queries.StaleCheques, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	stale := []int64{}
+	for _, id := range ids {
+		if _, err := m.SetChequeStatus(userID, id, ChequeStale, now.Format("2006-01-02")); err != nil {
+			return stale, err
+		}
+		stale = append(stale, id)
+	}
+	return stale, nil
+}
+
+// Cheques returns the cheque register filtered by account and status.
+// Empty filters select everything.
+func (m *AccountModel) Cheques(accountID, status string) ([]models.Cheque, error) {
+	aid, s := mysequel.NewNullString(accountID), mysequel.NewNullString(status)
+
+	var res []models.Cheque
+	err := mysequel.QueryToStructs(&res, m.DB, queries.Cheques, aid, aid, s, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ChequesPage returns a page of the cheque register filtered by account
+// and status, in account and cheque number order
+func (m *AccountModel) ChequesPage(accountID, status, cursor string, limit int) (models.ChequePage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.ChequePage{}, err
+	}
+	size := pageSize(limit)
+	aid, s := mysequel.NewNullString(accountID), mysequel.NewNullString(status)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.ChequesCount, aid, aid, s, s).Scan(&c.Total)
+		if err != nil {
+			return models.ChequePage{}, err
+		}
+	}
+
+	var res []models.Cheque
+	err = mysequel.QueryToStructs(&res, m.DB, queries.ChequesPage, append(append([]interface{}{aid, aid, s, s}, c.args(3)...), size+1)...)
+	if err != nil {
+		return models.ChequePage{}, err
+	}
+
+	page := models.ChequePage{Cheques: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Cheques = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.AccountID, last.Number, last.ID)
+	}
+	return page, nil
+}
+
+// PostDatedCheques returns issued cheques due after the given date ordered
+// by due date
+func (m *AccountModel) PostDatedCheques(date string) ([]models.Cheque, error) {
+	var res []models.Cheque
+	err := mysequel.QueryToStructs(&res, m.DB, queries.PostDatedCheques, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PostDatedChequesPage returns a page of PostDatedCheques
+func (m *AccountModel) PostDatedChequesPage(date, cursor string, limit int) (models.ChequePage, error) {
+	c, err := decodeCursor(cursor, 4)
+	if err != nil {
+		return models.ChequePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.PostDatedChequesCount, date).Scan(&c.Total)
+		if err != nil {
+			return models.ChequePage{}, err
+		}
+	}
+
+	var res []models.Cheque
+	args := append([]interface{}{date}, c.args(4)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.PostDatedChequesPage, append(args, size+1)...)
+	if err != nil {
+		return models.ChequePage{}, err
+	}
+
+	page := models.ChequePage{Cheques: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Cheques = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.DueDate, last.AccountID, last.Number, last.ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,185 @@
+package scribe
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubDB is an in-memory database for model tests. Queries are answered
+// with the rows given for their exact text, no rows otherwise, and every
+// other statement succeeds affecting one row unless affected says
+// otherwise. Statements are held until their transaction ends so that
+// tests can check what was committed.
+type stubDB struct {
+	mu        sync.Mutex
+	rows      map[string][][]driver.Value
+	affected  map[string]int64
+	lastID    int64
+	committed []stubExec
+	rollbacks int
+}
+
+type stubExec struct {
+	query string
+	args  []driver.Value
+}
+
+var (
+	stubsMu sync.Mutex
+	stubs   = make(map[string]*stubDB)
+)
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+// newStubDB opens a stub database answering queries with rows
+func newStubDB(t *testing.T, rows map[string][][]driver.Value) (*sql.DB, *stubDB) {
+	s := &stubDB{rows: rows}
+	stubsMu.Lock()
+	name := fmt.Sprintf("%s/%d", t.Name(), len(stubs))
+	stubs[name] = s
+	stubsMu.Unlock()
+
+	db, err := sql.Open("stub", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, s
+}
+
+// committedTo returns the committed statements that contain substr
+func (s *stubDB) committedTo(substr string) []stubExec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []stubExec
+	for _, e := range s.committed {
+		if strings.Contains(e.query, substr) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+type stubDriver struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	stubsMu.Lock()
+	defer stubsMu.Unlock()
+	s, ok := stubs[name]
+	if !ok {
+		return nil, fmt.Errorf("stub database %s does not exist", name)
+	}
+	return &stubConn{db: s}, nil
+}
+
+type stubConn struct {
+	db      *stubDB
+	inTx    bool
+	pending []stubExec
+}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	return &stubStmt{conn: c, query: query}, nil
+}
+
+func (c *stubConn) Close() error { return nil }
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *stubConn) Commit() error {
+	c.db.mu.Lock()
+	c.db.committed = append(c.db.committed, c.pending...)
+	c.db.mu.Unlock()
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *stubConn) Rollback() error {
+	c.db.mu.Lock()
+	c.db.rollbacks++
+	c.db.mu.Unlock()
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+type stubStmt struct {
+	conn  *stubConn
+	query string
+}
+
+func (s *stubStmt) Close() error  { return nil }
+func (s *stubStmt) NumInput() int { return -1 }
+
+func (s *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	db.lastID++
+	id := db.lastID
+	n, ok := db.affected[s.query]
+	if !ok {
+		n = 1
+	}
+	db.mu.Unlock()
+
+	e := stubExec{query: s.query, args: args}
+	if s.conn.inTx {
+		s.conn.pending = append(s.conn.pending, e)
+	} else {
+		db.mu.Lock()
+		db.committed = append(db.committed, e)
+		db.mu.Unlock()
+	}
+	return stubResult{id: id, affected: n}, nil
+}
+
+func (s *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return &stubRows{values: db.rows[s.query]}, nil
+}
+
+type stubResult struct {
+	id       int64
+	affected int64
+}
+
+func (r stubResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r stubResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type stubRows struct {
+	values [][]driver.Value
+	next   int
+}
+
+func (r *stubRows) Columns() []string {
+	if len(r.values) == 0 {
+		return nil
+	}
+	cols := make([]string, len(r.values[0]))
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d", i)
+	}
+	return cols
+}
+
+func (r *stubRows) Close() error { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.next == len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,276 @@
+package scribe
+
+import (
+	"database/sql"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/export"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// streamRows runs a query and passes every row to fn as strings, one row
+// at a time. NULL values are passed as empty strings.
+func streamRows(db *sql.DB, q string, fn func(cols []string) error, args ...interface{}) error {
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	vals := make([]sql.NullString, len(names))
+	dest := make([]interface{}, len(names))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+
+	cols := make([]string, len(names))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			cols[i] = v.String
+		}
+		if err := fn(cols); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// linked formats a linked transaction ID, leaving unlinked rows blank
+func linked(v string) string {
+	if v == "0" {
+		return ""
+	}
+	return v
+}
+
+// subtotaler writes detail rows and inserts subtotal rows whenever one of
+// the leading group columns changes, followed by a grand total on close
+type subtotaler struct {
+	w       export.Writer
+	groups  int
+	numeric []int
+	keys    []string
+	sums    [][]int64
+	started bool
+}
+
+func newSubtotaler(w export.Writer, groups int, numeric []int) *subtotaler {
+	s := &subtotaler{w: w, groups: groups, numeric: numeric, keys: make([]string, groups)}
+	s.sums = make([][]int64, groups+1)
+	for i := range s.sums {
+		s.sums[i] = make([]int64, len(numeric))
+	}
+	return s
+}
+
+// row writes a detail row. Numeric cells are normalised to two decimals.
+func (s *subtotaler) row(ncols int, cells []string) error {
+	if s.started {
+		for level := 0; level < s.groups; level++ {
+			if cells[level] != s.keys[level] {
+				if err := s.flush(ncols, level); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	s.started = true
+	copy(s.keys, cells[:s.groups])
+
+	for i, c := range s.numeric {
+		// Blank cells, such as the credit of a debit line, count as zero
+		var amount int64
+		if cells[c] != "" {
+			var err error
+			amount, err = parseSignedAmount(cells[c])
+			if err != nil {
+				return err
+			}
+		}
+		cells[c] = formatAmount(amount)
+		for level := range s.sums {
+			s.sums[level][i] += amount
+		}
+	}
+	return s.w.Row(export.Detail, cells)
+}
+
+// flush writes subtotals of the innermost group up to and including level
+func (s *subtotaler) flush(ncols, level int) error {
+	for l := s.groups - 1; l >= level; l-- {
+		cells := make([]string, ncols)
+		copy(cells, s.keys[:l])
+		cells[l] = "Total " + s.keys[l]
+		for i, c := range s.numeric {
+			cells[c] = formatAmount(s.sums[l][i])
+			s.sums[l][i] = 0
+		}
+		if err := s.w.Row(export.Subtotal, cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close writes the outstanding subtotals and the grand total
+func (s *subtotaler) close(ncols int) error {
+	if s.started {
+		if err := s.flush(ncols, 0); err != nil {
+			return err
+		}
+	}
+	cells := make([]string, ncols)
+	cells[0] = "Total"
+	for i, c := range s.numeric {
+		cells[c] = formatAmount(s.sums[s.groups][i])
+	}
+	return s.w.Row(export.Total, cells)
+}
+
+// ExportTrialBalance streams the trial balance with subtotals by main and
+// sub account
+func (m *AccountModel) ExportTrialBalance(w export.Writer, meta export.Metadata, postingDate string) error {
+	columns := []export.Column{{Title: "Main Account"}, {Title: "Sub Account"}, {Title: "Category"}, {Title: "Account ID"}, {Title: "Account"}, {Title: "Debit", Numeric: true}, {Title: "Credit", Numeric: true}}
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	s := newSubtotaler(w, 2, []int{5, 6})
+	err := streamRows(m.DB, queries.TrialBalance, func(cols []string) error {
+		return s.row(len(columns), append([]string{}, cols[1:]...))
+	}, postingDate, postingDate)
+	if err != nil {
+		return err
+	}
+	if err := s.close(len(columns)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportBalanceSheetSummary streams the balance sheet summary with
+// subtotals by main and sub account
+func (m *AccountModel) ExportBalanceSheetSummary(w export.Writer, meta export.Metadata, postingDate string) error {
+	columns := []export.Column{{Title: "Main Account"}, {Title: "Sub Account"}, {Title: "Category"}, {Title: "Amount", Numeric: true}}
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	s := newSubtotaler(w, 2, []int{3})
+	err := streamRows(m.DB, queries.BalanceSheetSummary, func(cols []string) error {
+		return s.row(len(columns), append([]string{}, cols...))
+	}, postingDate)
+	if err != nil {
+		return err
+	}
+	if err := s.close(len(columns)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportPNL streams the profit and loss accounts with subtotals by main
+// and sub account
+func (m *AccountModel) ExportPNL(w export.Writer, meta export.Metadata, startDate, endDate string) error {
+	columns := []export.Column{{Title: "Main Account"}, {Title: "Sub Account"}, {Title: "Category"}, {Title: "Account"}, {Title: "Amount", Numeric: true}}
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	s := newSubtotaler(w, 2, []int{4})
+	err := streamRows(m.DB, queries.AccountSummariesForPnl, func(cols []string) error {
+		return s.row(len(columns), append([]string{}, cols[1:]...))
+	}, startDate, endDate)
+	if err != nil {
+		return err
+	}
+	if err := s.close(len(columns)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportLedger streams an account ledger between two posting dates with
+// the balance brought forward and a running balance. Reversed accruals
+// show the transaction on the other leg.
+func (m *AccountModel) ExportLedger(w export.Writer, meta export.Metadata, aid int, startDate, endDate string) error {
+	columns := []export.Column{{Title: "Posting Date"}, {Title: "Transaction"}, {Title: "Linked"}, {Title: "Remark"}, {Title: "Counterparties"}, {Title: "Debit", Numeric: true}, {Title: "Credit", Numeric: true}, {Title: "Balance", Numeric: true}}
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	start, end := mysequel.NewNullString(startDate), mysequel.NewNullString(endDate)
+
+	var opening float64
+	err := m.DB.QueryRow(queries.LedgerOpeningBalance, aid, start).Scan(&opening)
+	if err != nil {
+		return err
+	}
+	balance := toCents(opening)
+	if err := w.Row(export.Subtotal, []string{startDate, "", "", "Balance brought forward", "", "", "", formatAmount(balance)}); err != nil {
+		return err
+	}
+
+	var debits, credits int64
+	err = streamRows(m.DB, queries.LedgerStatement, func(cols []string) error {
+		amount, err := parseAmount(cols[4])
+		if err != nil {
+			return err
+		}
+		debit, credit := "", ""
+		if cols[3] == "DR" {
+			debits += amount
+			balance += amount
+			debit = formatAmount(amount)
+		} else {
+			credits += amount
+			balance -= amount
+			credit = formatAmount(amount)
+		}
+		return w.Row(export.Detail, []string{cols[2], cols[1], linked(cols[7]), cols[5], cols[6], debit, credit, formatAmount(balance)})
+	}, aid, start, start, end, end)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Row(export.Total, []string{endDate, "", "", "Closing balance", "", formatAmount(debits), formatAmount(credits), formatAmount(balance)}); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportJournalEntriesForAudit streams journal entries for audit with
+// subtotals by transaction
+func (m *AccountModel) ExportJournalEntriesForAudit(w export.Writer, meta export.Metadata, date, postingDate string) error {
+	columns := []export.Column{{Title: "Transaction"}, {Title: "Linked"}, {Title: "Date Time"}, {Title: "Issuer"}, {Title: "Account"}, {Title: "Posting Date"}, {Title: "Remark"}, {Title: "Debit", Numeric: true}, {Title: "Credit", Numeric: true}}
+	if err := w.Header(meta, columns); err != nil {
+		return err
+	}
+
+	d, pDate := mysequel.NewNullString(date), mysequel.NewNullString(postingDate)
+	s := newSubtotaler(w, 1, []int{7, 8})
+	err := streamRows(m.DB, queries.JournalEntriesForAudit, func(cols []string) error {
+		debit, credit := "", ""
+		if cols[4] == "DR" {
+			debit = cols[6]
+		} else {
+			credit = cols[6]
+		}
+		return s.row(len(columns), []string{cols[2], linked(cols[9]), cols[0], cols[1], cols[3], cols[5], cols[7], debit, credit})
+	}, d, d, pDate, pDate)
+	if err != nil {
+		return err
+	}
+	if err := s.close(len(columns)); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSV returns a Writer producing CSV. The letterhead is written as
+// single cell rows followed by a blank row.
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Header(meta Metadata, columns []Column) error {
+	lines := meta.lines()
+	for _, l := range lines {
+		if err := c.w.Write([]string{l}); err != nil {
+			return err
+		}
+	}
+	if len(lines) > 0 {
+		if err := c.w.Write([]string{""}); err != nil {
+			return err
+		}
+	}
+
+	titles := make([]string, len(columns))
+	for i, col := range columns {
+		titles[i] = col.Title
+	}
+	return c.w.Write(titles)
+}
+
+func (c *csvWriter) Row(kind RowKind, cells []string) error {
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,56 @@
+// Package export provides streaming writers that render tabular reports
+// as CSV, XLSX or PDF
+package export
+
+import "time"
+
+// RowKind distinguishes detail lines from total lines
+type RowKind int
+
+// Row kinds
+const (
+	Detail RowKind = iota
+	Subtotal
+	Total
+)
+
+// Metadata holds the letterhead printed above a report
+type Metadata struct {
+	Company     string
+	Address     string
+	Title       string
+	Subtitle    string
+	GeneratedAt time.Time
+}
+
+// lines returns the non-empty letterhead lines
+func (m Metadata) lines() []string {
+	var lines []string
+	for _, l := range []string{m.Company, m.Address, m.Title, m.Subtitle} {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if !m.GeneratedAt.IsZero() {
+		lines = append(lines, "Generated "+m.GeneratedAt.Format("2006-01-02 15:04:05"))
+	}
+	return lines
+}
+
+// Column describes a report column. Numeric columns are right aligned and
+// written as numbers where the format supports it.
+type Column struct {
+	Title   string
+	Numeric bool
+}
+
+// Writer writes a report one row at a time without holding earlier rows
+type Writer interface {
+	// Header writes the letterhead and the column titles. It must be
+	// called once before any rows are written.
+	Header(meta Metadata, columns []Column) error
+	// Row writes a line of the report
+	Row(kind RowKind, cells []string) error
+	// Close finishes the report. The underlying io.Writer is not closed.
+	Close() error
+}
@@ -0,0 +1,129 @@
+package export
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ssrdive/scribe/internal/pdf"
+)
+
+// Landscape A4 page geometry in points
+const (
+	pdfPageWidth  = 842
+	pdfPageHeight = 595
+	pdfMargin     = 36
+	pdfFontSize   = 8
+	pdfLineHeight = 11
+)
+
+type pdfWriter struct {
+	w       *pdf.Writer
+	page    *bytes.Buffer
+	y       float64
+	columns []Column
+	x       []float64
+	widths  []float64
+}
+
+// NewPDF returns a Writer producing a landscape A4 PDF. Each page is
+// written out as soon as it is full.
+func NewPDF(w io.Writer) Writer {
+	return &pdfWriter{w: pdf.NewWriter(w)}
+}
+
+func (p *pdfWriter) Header(meta Metadata, columns []Column) error {
+	// Text columns are given twice the width of numeric columns
+	p.columns = columns
+	var weights float64
+	for _, c := range columns {
+		if c.Numeric {
+			weights++
+		} else {
+			weights += 2
+		}
+	}
+	x := float64(pdfMargin)
+	unit := (pdfPageWidth - 2*pdfMargin) / weights
+	for _, c := range columns {
+		w := unit * 2
+		if c.Numeric {
+			w = unit
+		}
+		p.x = append(p.x, x)
+		p.widths = append(p.widths, w)
+		x += w
+	}
+
+	p.newPage()
+	for i, l := range meta.lines() {
+		size := 10.0
+		font := pdf.Regular
+		if i == 0 {
+			size, font = 14, pdf.Bold
+		}
+		p.page.WriteString(pdf.Text(font, size, pdfMargin, p.y, l))
+		p.y -= size + 4
+	}
+	p.y -= pdfLineHeight
+	p.titles()
+	return nil
+}
+
+func (p *pdfWriter) newPage() {
+	p.page = &bytes.Buffer{}
+	p.y = pdfPageHeight - pdfMargin - pdfLineHeight
+}
+
+func (p *pdfWriter) titles() {
+	titles := make([]string, len(p.columns))
+	for i, c := range p.columns {
+		titles[i] = c.Title
+	}
+	p.line(titles, true)
+	p.page.WriteString(pdf.Line(0.5, pdfMargin, p.y+pdfLineHeight-2, pdfPageWidth-pdfMargin, p.y+pdfLineHeight-2))
+}
+
+// line writes a row of cells at the current position and moves down
+func (p *pdfWriter) line(cells []string, bold bool) {
+	font := pdf.Regular
+	if bold {
+		font = pdf.Bold
+	}
+	for i, v := range cells {
+		if i >= len(p.columns) || v == "" {
+			continue
+		}
+		v = pdf.Truncate(v, pdfFontSize, p.widths[i]-4)
+		x := p.x[i]
+		if p.columns[i].Numeric {
+			x = p.x[i] + p.widths[i] - 2 - pdf.TextWidth(v, pdfFontSize)
+		}
+		p.page.WriteString(pdf.Text(font, pdfFontSize, x, p.y, v))
+	}
+	p.y -= pdfLineHeight
+}
+
+func (p *pdfWriter) Row(kind RowKind, cells []string) error {
+	if p.y < pdfMargin {
+		if err := p.w.Page(pdfPageWidth, pdfPageHeight, p.page.String()); err != nil {
+			return err
+		}
+		p.newPage()
+		p.titles()
+	}
+	if kind != Detail {
+		p.page.WriteString(pdf.Line(0.3, pdfMargin, p.y+pdfLineHeight-2, pdfPageWidth-pdfMargin, p.y+pdfLineHeight-2))
+	}
+	p.line(cells, kind != Detail)
+	return nil
+}
+
+func (p *pdfWriter) Close() error {
+	if p.page == nil {
+		return nil
+	}
+	if err := p.w.Page(pdfPageWidth, pdfPageHeight, p.page.String()); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
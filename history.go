@@ -0,0 +1,212 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// historyValue formats a column value the way mysequel stores it
+func historyValue(v interface{}) string {
+	switch v := v.(type) {
+	case sql.NullString:
+		return v.String
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// recordChange stores the change of a single field
+func recordChange(tx *sql.Tx, userID, table string, id int64, field, oldValue, newValue string) error {
+	_, err := mysequel.Insert(mysequel.Table{
+		TableName: "change_log",
+		Columns:   []string{"table_name", "record_id", "field", "old_value", "new_value", "user_id", "datetime"},
+		Vals:      []interface{}{table, id, field, oldValue, newValue, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	return err
+}
+
+// recordCreate stores the initial values of a newly inserted record
+func recordCreate(tx *sql.Tx, userID, table string, id int64, cols []string, vals []interface{}) error {
+	for i, c := range cols {
+		v := historyValue(vals[i])
+		if v == "" {
+			continue
+		}
+		if err := recordChange(tx, userID, table, id, c, "", v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateRecord updates columns of a record by ID and stores a change for
+// every field whose value differs from the current one
+func updateRecord(tx *sql.Tx, userID, table string, id int64, cols []string, vals []interface{}) error {
+	if !identifier.MatchString(table) {
+		return fmt.Errorf("invalid table %s", table)
+	}
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		if !identifier.MatchString(c) {
+			return fmt.Errorf("invalid column %s", c)
+		}
+		quoted[i] = fmt.Sprintf("COALESCE(`%s`, '')", c)
+	}
+
+	current := make([]string, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range current {
+		dest[i] = &current[i]
+	}
+	q := fmt.Sprintf("SELECT %s FROM `%s` WHERE id = ? FOR UPDATE", strings.Join(quoted, ", "), table)
+	err := tx.QueryRow(q, id).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%s %d does not exist", table, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// mysequel formats values as text and stores empty ones as NULL
+	values := make([]interface{}, len(vals))
+	for i, v := range vals {
+		values[i] = historyValue(v)
+	}
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: table,
+			Columns:   cols,
+			Vals:      values,
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{fmt.Sprint(id)},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, c := range cols {
+		if v := historyValue(vals[i]); v != current[i] {
+			if err := recordChange(tx, userID, table, id, c, current[i], v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// updateForm updates the form fields named in params of a record and
+// records the changes
+func (m *AccountModel) updateForm(userID, table string, id int64, params []string, form url.Values) error {
+	if len(params) == 0 {
+		return errors.New("no fields to update")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	vals := make([]interface{}, len(params))
+	for i, p := range params {
+		vals[i] = mysequel.NewNullString(form.Get(p))
+	}
+
+	err = updateRecord(tx, userID, table, id, params, vals)
+	return err
+}
+
+// UpdateAccount updates the fields of an account named in params
+func (m *AccountModel) UpdateAccount(userID string, id int64, params []string, form url.Values) error {
+	return m.updateForm(userID, "account", id, params, form)
+}
+
+// UpdateCategory updates the fields of a category named in params.
+// Categories are moved within the chart with ImportChart.
+func (m *AccountModel) UpdateCategory(userID string, id int64, params []string, form url.Values) error {
+	if containsString(params, "parent_id") || containsString(params, "sub_account_id") {
+		return errors.New("categories are moved with ImportChart")
+	}
+	return m.updateForm(userID, "account_category", id, params, form)
+}
+
+// UpdatePaymentVoucher updates the fields of a payment voucher named in
+// params, such as due_date, check_number or payee. The number of a cheque
+// in the cheque register cannot be changed.
+func (m *AccountModel) UpdatePaymentVoucher(userID string, id int64, params []string, form url.Values) error {
+	if containsString(params, "check_number") {
+		var cheques int
+		err := m.DB.QueryRow(queries.ChequeForVoucher, id).Scan(&cheques)
+		if err != nil {
+			return err
+		}
+		if cheques > 0 {
+			return errors.New("registered cheques are cancelled and reissued, not renumbered")
+		}
+	}
+	return m.updateForm(userID, "payment_voucher", id, params, form)
+}
+
+// History returns the change history of a record, oldest first
+func (m *AccountModel) History(table string, id int64) ([]models.Change, error) {
+	var res []models.Change
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ChangeHistory, table, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// HistoryPage returns a page of the change history of a record, oldest
+// first
+func (m *AccountModel) HistoryPage(table string, id int64, cursor string, limit int) (models.ChangePage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.ChangePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.ChangeHistoryCount, table, id).Scan(&c.Total)
+		if err != nil {
+			return models.ChangePage{}, err
+		}
+	}
+
+	var res []models.Change
+	args := append([]interface{}{table, id}, c.args(1)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.ChangeHistoryPage, append(args, size+1)...)
+	if err != nil {
+		return models.ChangePage{}, err
+	}
+
+	page := models.ChangePage{Changes: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Changes = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,35 @@
+package scribe
+
+import (
+	"database/sql/driver"
+	"net/url"
+	"testing"
+)
+
+func TestUpdateFormEmptyField(t *testing.T) {
+	db, stub := newStubDB(t, map[string][][]driver.Value{
+		"SELECT COALESCE(`description`, ''), COALESCE(`name`, '') FROM `account` WHERE id = ? FOR UPDATE": {{"", "Cash"}},
+	})
+	m := &AccountModel{DB: db}
+
+	form := url.Values{"description": {""}, "name": {"Petty cash"}}
+	if err := m.UpdateAccount("1", 7, []string{"description", "name"}, form); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := stub.committedTo("UPDATE `account`")
+	if len(updates) != 1 {
+		t.Fatalf("got %d updates, want 1", len(updates))
+	}
+	if args := updates[0].args; args[0] != nil || args[1] != "Petty cash" {
+		t.Errorf("got update values %v, want NULL and Petty cash", args)
+	}
+
+	changes := stub.committedTo("change_log")
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	if args := changes[0].args; args[2] != "name" || args[3] != "Cash" || args[4] != "Petty cash" {
+		t.Errorf("got change %v, want name from Cash to Petty cash", args)
+	}
+}
@@ -0,0 +1,144 @@
+package scribe
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// RowError describes a problem with a single row of an imported file.
+// Rows are numbered from 1 in the order given, excluding any header row.
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// ImportError holds every row error found while validating an import
+type ImportError []RowError
+
+func (e ImportError) Error() string {
+	msgs := make([]string, len(e))
+	for i, re := range e {
+		msgs[i] = re.Error()
+	}
+	return fmt.Sprintf("%d invalid rows: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// add records an error against a row
+func (e *ImportError) add(row int, format string, a ...interface{}) {
+	*e = append(*e, RowError{Row: row, Message: fmt.Sprintf(format, a...)})
+}
+
+// debitCredit parses the debit and credit amounts of a row, recording an
+// error unless the amounts are valid and at most one of them is non-zero
+func (e *ImportError) debitCredit(row int, debit, credit string) (int64, int64, bool) {
+	var dr, cr int64
+	var err error
+	valid := true
+	if debit != "" {
+		if dr, err = parseAmount(debit); err != nil {
+			e.add(row, "debit: %v", err)
+			valid = false
+		}
+	}
+	if credit != "" {
+		if cr, err = parseAmount(credit); err != nil {
+			e.add(row, "credit: %v", err)
+			valid = false
+		}
+	}
+	if dr != 0 && cr != 0 {
+		e.add(row, "only one of debit or credit may be given")
+		valid = false
+	}
+	return dr, cr, valid
+}
+
+// err returns nil when no rows are in error so that the result can be
+// returned directly as an error value. Errors are sorted by row.
+func (e ImportError) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Row < e[j].Row })
+	return e
+}
+
+// accountsByCode returns account IDs keyed by account code
+func accountsByCode(db mysequel.QueryRunner) (map[string]int, error) {
+	var codes []models.AccountCode
+	err := mysequel.QueryToStructs(&codes, db, queries.AccountCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(codes))
+	for _, c := range codes {
+		ids[c.AccountID] = c.ID
+	}
+	return ids, nil
+}
+
+// csvRecord is a data row of a CSV file keyed by header name
+type csvRecord struct {
+	row    int
+	fields map[string]string
+}
+
+// readCSV reads a CSV file with a header row. Header names are matched
+// case-insensitively and every required column must be present.
+func readCSV(r io.Reader, required []string) ([]csvRecord, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, c := range required {
+		if _, ok := cols[c]; !ok {
+			return nil, fmt.Errorf("missing column %s", c)
+		}
+	}
+
+	var records []csvRecord
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make(map[string]string, len(cols))
+		for c, i := range cols {
+			if i < len(rec) {
+				fields[c] = strings.TrimSpace(rec[i])
+			}
+		}
+		records = append(records, csvRecord{row: row, fields: fields})
+	}
+
+	return records, nil
+}
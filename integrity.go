@@ -0,0 +1,193 @@
+package scribe
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Transaction log events. A posted transaction is logged with its content
+// and a removed transaction, such as replaced opening balances, is logged
+// as void.
+const (
+	LogPost = "POST"
+	LogVoid = "VOID"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func chainHash(previousHash, event string, tid int64, contentHash string) string {
+	return sha256Hex(fmt.Sprintf("%s|%s|%d|%s", previousHash, event, tid, contentHash))
+}
+
+// transactionContentHash hashes a transaction and its lines in a canonical
+// form. It returns an empty hash when the transaction does not exist.
+func transactionContentHash(db mysequel.QueryRunner, tid int64) (string, error) {
+	rows, err := db.Query(queries.TransactionForLog, tid)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	found := false
+	for rows.Next() {
+		var userID, datetime, postingDate, contractID, remark string
+		if err := rows.Scan(&userID, &datetime, &postingDate, &contractID, &remark); err != nil {
+			rows.Close()
+			return "", err
+		}
+		fmt.Fprintf(&b, "%d|%s|%s|%s|%s|%q\n", tid, userID, datetime, postingDate, contractID, remark)
+		found = true
+	}
+	rows.Close()
+	if !found {
+		return "", nil
+	}
+
+	rows, err = db.Query(queries.TransactionLinesForLog, tid)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var accountID, typ, amount string
+		if err := rows.Scan(&accountID, &typ, &amount); err != nil {
+			return "", err
+		}
+		cents, err := parseAmount(amount)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s|%s|%s\n", accountID, typ, formatAmount(cents))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return sha256Hex(b.String()), nil
+}
+
+// appendTransactionLog chains an event for a transaction onto the log.
+// The single row of transaction_log_lock is locked, even while the log is
+// empty, so that concurrent postings are chained one after the other. The
+// last entry is read with a locking read to see the latest committed hash.
+func appendTransactionLog(tx *sql.Tx, tid int64, event string) error {
+	_, err := tx.Exec(queries.CreateTransactionLogLock)
+	if err != nil {
+		return err
+	}
+	var lock int
+	err = tx.QueryRow(queries.TransactionLogLock).Scan(&lock)
+	if err != nil {
+		return err
+	}
+
+	var previous string
+	err = tx.QueryRow(queries.LastTransactionLog).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	content := ""
+	if event == LogPost {
+		content, err = transactionContentHash(tx, tid)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "transaction_log",
+		Columns:   []string{"transaction_id", "event", "content_hash", "previous_hash", "hash", "datetime"},
+		Vals:      []interface{}{tid, event, content, previous, chainHash(previous, event, tid, content), time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	return err
+}
+
+// VerifyIntegrity walks the transaction log from the start, checking that
+// every entry is chained to the one before it and that the latest entry
+// of each transaction matches the transaction as it is stored now. It
+// also reports transactions posted after logging began that were never
+// logged. The first problem found is reported.
+func (m *AccountModel) VerifyIntegrity() (models.IntegrityReport, error) {
+	var report models.IntegrityReport
+
+	last := make(map[int]int)
+	rows, err := m.DB.Query(queries.LastTransactionLogEvents)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var tid, id int
+		if err := rows.Scan(&tid, &id); err != nil {
+			rows.Close()
+			return report, err
+		}
+		last[tid] = id
+	}
+	rows.Close()
+
+	var entries []models.TransactionLogEntry
+	err = mysequel.QueryToStructs(&entries, m.DB, queries.TransactionLog)
+	if err != nil {
+		return report, err
+	}
+
+	fail := func(e models.TransactionLogEntry, problem string) (models.IntegrityReport, error) {
+		report.LogID = e.ID
+		report.TransactionID = e.TransactionID
+		report.Problem = problem
+		return report, nil
+	}
+
+	previous := ""
+	for _, e := range entries {
+		if e.PreviousHash != previous {
+			return fail(e, "log entry does not follow the previous entry, entries are missing or altered")
+		}
+		if e.Hash != chainHash(e.PreviousHash, e.Event, int64(e.TransactionID), e.ContentHash) {
+			return fail(e, "log entry hash does not match its contents")
+		}
+		previous = e.Hash
+
+		if last[e.TransactionID] == e.ID {
+			content, err := transactionContentHash(m.DB, int64(e.TransactionID))
+			if err != nil {
+				return report, err
+			}
+			switch {
+			case e.Event == LogPost && content == "":
+				return fail(e, "transaction is missing")
+			case e.Event == LogPost && content != e.ContentHash:
+				return fail(e, "transaction has been altered")
+			case e.Event == LogVoid && content != "":
+				return fail(e, "void transaction still exists")
+			}
+		}
+		report.Verified++
+	}
+
+	var unlogged sql.NullInt64
+	err = m.DB.QueryRow(queries.UnloggedTransaction).Scan(&unlogged)
+	if err != nil && err != sql.ErrNoRows {
+		return report, err
+	}
+	if unlogged.Valid {
+		report.TransactionID = int(unlogged.Int64)
+		report.Problem = "transaction is not in the log"
+		return report, nil
+	}
+
+	report.Valid = true
+	return report, nil
+}
@@ -0,0 +1,160 @@
+// Package pdf writes minimal PDF 1.4 documents using the standard
+// Helvetica fonts, one page at a time
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Fonts available to page content
+const (
+	Regular = "F1"
+	Bold    = "F2"
+)
+
+// helveticaWidths holds the glyph widths of printable ASCII characters in
+// thousandths of an em, used to align and truncate text
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// TextWidth estimates the width of s in points at the given font size
+func TextWidth(s string, size float64) float64 {
+	var w int
+	for _, r := range s {
+		if r >= 32 && r < 127 {
+			w += helveticaWidths[r-32]
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// Truncate shortens s by whole runes until it fits the width
+func Truncate(s string, size, width float64) string {
+	for r := []rune(s); len(r) > 0 && TextWidth(s, size) > width; {
+		r = r[:len(r)-1]
+		s = string(r)
+	}
+	return s
+}
+
+// Escape converts s to a PDF literal string in WinAnsi encoding
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Text returns the content operators drawing s with its baseline starting
+// at x, y
+func Text(font string, size, x, y float64, s string) string {
+	return fmt.Sprintf("BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, Escape(s))
+}
+
+// Line returns the content operators drawing a line of the given width
+func Line(width, x1, y1, x2, y2 float64) string {
+	return fmt.Sprintf("%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// countingWriter tracks the byte offset needed for the PDF cross reference
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// Writer writes a document. Pages are written out as soon as they are
+// added, so only the object offsets are held until Close.
+type Writer struct {
+	w       *countingWriter
+	offsets []int64
+	pages   []int
+	started bool
+}
+
+// NewWriter returns a Writer writing to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: &countingWriter{w: w}}
+}
+
+// Objects 1 to 4 are the catalog, the page tree and the two fonts. Page
+// contents and pages are numbered from 5 in the order they are written.
+func (p *Writer) object(n int, body string) {
+	for len(p.offsets) <= n {
+		p.offsets = append(p.offsets, 0)
+	}
+	p.offsets[n] = p.w.n
+	fmt.Fprintf(p.w, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+func (p *Writer) start() {
+	if p.started {
+		return
+	}
+	p.started = true
+	fmt.Fprint(p.w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	p.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	p.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	p.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+}
+
+// Page writes a page of the given size in points with its content
+// operators
+func (p *Writer) Page(width, height float64, content string) error {
+	p.start()
+	n := len(p.offsets)
+	p.object(n, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	p.object(n+1, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> >>", width, height, n))
+	p.pages = append(p.pages, n+1)
+	return p.w.err
+}
+
+// Close writes the page tree and the cross reference. The underlying
+// io.Writer is not closed.
+func (p *Writer) Close() error {
+	p.start()
+	kids := make([]string, len(p.pages))
+	for i, n := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	p.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+
+	xref := p.w.n
+	fmt.Fprintf(p.w, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets))
+	for _, off := range p.offsets[1:] {
+		fmt.Fprintf(p.w, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(p.w, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets), xref)
+	return p.w.err
+}
@@ -0,0 +1,168 @@
+package scribe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+// journalBatchTransaction is a transaction assembled from import lines
+type journalBatchTransaction struct {
+	key            string
+	row            int
+	postingDate    string
+	remark         string
+	debits         int64
+	credits        int64
+	journalEntries []models.JournalEntry
+}
+
+// ImportJournals reads a multi-transaction journal CSV file with
+// transaction_key, posting_date, account_id, debit, credit and remark
+// columns and posts it with JournalBatch
+func (m *AccountModel) ImportJournals(userID string, r io.Reader) ([]int64, error) {
+	records, err := readCSV(r, []string{"transaction_key", "posting_date", "account_id", "debit", "credit"})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]models.JournalImportLine, len(records))
+	for i, rec := range records {
+		lines[i] = models.JournalImportLine{
+			TransactionKey: rec.fields["transaction_key"],
+			PostingDate:    rec.fields["posting_date"],
+			AccountID:      rec.fields["account_id"],
+			Debit:          rec.fields["debit"],
+			Credit:         rec.fields["credit"],
+			Remark:         rec.fields["remark"],
+		}
+	}
+
+	return m.JournalBatch(userID, lines)
+}
+
+// JournalBatch validates every line and transaction of a journal batch up
+// front and posts all transactions in a single database transaction. Row
+// errors are reported together as an ImportError and nothing is posted
+// unless the whole batch is valid. Each transaction is numbered as a
+// journal voucher. Transaction IDs are returned in the order the
+// transaction keys first appear.
+func (m *AccountModel) JournalBatch(userID string, lines []models.JournalImportLine) ([]int64, error) {
+	if m.RequireApproval {
+		return nil, ErrApprovalRequired
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	accounts, err := accountsByCode(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := journalBatch(accounts, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	tids := make([]int64, len(batch))
+	for i, t := range batch {
+		tids[i], err = CreateTransaction(tx, userID, t.postingDate, "", t.remark)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", t.key, err)
+		}
+
+		_, err = allocateDocumentNumber(tx, DocumentJournal, m.Branch, t.postingDate, tids[i])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", t.key, err)
+		}
+
+		err = IssueJournalEntries(tx, tids[i], t.journalEntries)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", t.key, err)
+		}
+	}
+
+	return tids, nil
+}
+
+// journalBatch groups import lines into transactions and validates them
+func journalBatch(accounts map[string]int, lines []models.JournalImportLine) ([]*journalBatchTransaction, error) {
+	var ierr ImportError
+	var batch []*journalBatchTransaction
+	byKey := make(map[string]*journalBatchTransaction)
+
+	for i, l := range lines {
+		row := i + 1
+		if l.TransactionKey == "" {
+			ierr.add(row, "transaction_key is required")
+			continue
+		}
+
+		t, ok := byKey[l.TransactionKey]
+		if !ok {
+			t = &journalBatchTransaction{key: l.TransactionKey, row: row, postingDate: l.PostingDate, remark: l.Remark}
+			byKey[l.TransactionKey] = t
+			batch = append(batch, t)
+
+			if err := validatePostingDate(l.PostingDate); err != nil {
+				ierr.add(row, "%v", err)
+			}
+		} else if l.PostingDate != t.postingDate {
+			ierr.add(row, "posting date differs from row %d of transaction %s", t.row, t.key)
+		}
+		if t.remark == "" {
+			t.remark = l.Remark
+		}
+
+		aid, known := accounts[l.AccountID]
+		if !known {
+			ierr.add(row, "account %s does not exist", l.AccountID)
+		}
+		debit, credit, valid := ierr.debitCredit(row, l.Debit, l.Credit)
+		if valid && debit == 0 && credit == 0 {
+			ierr.add(row, "debit or credit is required")
+			continue
+		}
+		if !known || !valid {
+			continue
+		}
+
+		t.debits += debit
+		t.credits += credit
+		entry := models.JournalEntry{Account: strconv.Itoa(aid)}
+		if debit != 0 {
+			entry.Debit = formatAmount(debit)
+		} else {
+			entry.Credit = formatAmount(credit)
+		}
+		t.journalEntries = append(t.journalEntries, entry)
+	}
+
+	for _, t := range batch {
+		if t.debits != t.credits {
+			ierr.add(t.row, "transaction %s does not balance: debits %s, credits %s", t.key, formatAmount(t.debits), formatAmount(t.credits))
+		}
+	}
+
+	if err := ierr.err(); err != nil {
+		return nil, err
+	}
+	if len(batch) == 0 {
+		return nil, errors.New("no transactions given")
+	}
+
+	return batch, nil
+}
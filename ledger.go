@@ -0,0 +1,136 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// LedgerStatement returns the ledger of an account between two posting
+// dates, inclusive, with the opening balance brought forward and a running
+// balance on every line. Either date may be empty to leave the range open.
+func (m *AccountModel) LedgerStatement(aid int, startDate, endDate string) (models.LedgerStatement, error) {
+	var name string
+	err := m.DB.QueryRow(queries.AccountName, aid).Scan(&name)
+	if err == sql.ErrNoRows {
+		return models.LedgerStatement{}, errors.New("account does not exist")
+	}
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	start, end := mysequel.NewNullString(startDate), mysequel.NewNullString(endDate)
+
+	var opening float64
+	err = m.DB.QueryRow(queries.LedgerOpeningBalance, aid, start).Scan(&opening)
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	var entries []models.LedgerStatementEntry
+	err = mysequel.QueryToStructs(&entries, m.DB, queries.LedgerStatement, aid, start, start, end, end)
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	return ledgerStatement(aid, name, startDate, endDate, opening, entries), nil
+}
+
+// LedgerStatementPage returns a page of LedgerStatement. The opening
+// balance is the balance brought forward to the first line of the page
+// and debits and credits are totals of the page.
+func (m *AccountModel) LedgerStatementPage(aid int, startDate, endDate, cursor string, limit int) (models.LedgerStatement, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+	size := pageSize(limit)
+
+	var name string
+	err = m.DB.QueryRow(queries.AccountName, aid).Scan(&name)
+	if err == sql.ErrNoRows {
+		return models.LedgerStatement{}, errors.New("account does not exist")
+	}
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	start, end := mysequel.NewNullString(startDate), mysequel.NewNullString(endDate)
+
+	var opening float64
+	if c.first() {
+		err = m.DB.QueryRow(queries.LedgerOpeningBalance, aid, start).Scan(&opening)
+		if err == nil {
+			err = m.DB.QueryRow(queries.LedgerStatementCount, aid, start, start, end, end).Scan(&c.Total)
+		}
+	} else {
+		err = m.DB.QueryRow(queries.LedgerBalanceThrough, aid, c.Keys[0], c.Keys[1], c.Keys[2]).Scan(&opening)
+	}
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	args := append([]interface{}{aid, start, start, end, end}, c.args(3)...)
+	var entries []models.LedgerStatementEntry
+	err = mysequel.QueryToStructs(&entries, m.DB, queries.LedgerStatementPage, append(args, size+1)...)
+	if err != nil {
+		return models.LedgerStatement{}, err
+	}
+
+	var next string
+	if len(entries) > size {
+		entries = entries[:size]
+		last := entries[size-1]
+		next = encodeCursor(c.Total, last.PostingDate, last.TransactionID, last.ID)
+	}
+
+	res := ledgerStatement(aid, name, startDate, endDate, opening, entries)
+	res.NextCursor = next
+	res.TotalEstimate = c.Total
+	return res, nil
+}
+
+// ledgerStatement computes running balances from the opening balance.
+// Balances are accumulated in cents so that long ledgers do not drift.
+func ledgerStatement(aid int, name, startDate, endDate string, opening float64, entries []models.LedgerStatementEntry) models.LedgerStatement {
+	balance := toCents(opening)
+	var debits, credits int64
+	lines := make([]models.LedgerStatementLine, len(entries))
+	for i, e := range entries {
+		amount := toCents(e.Amount)
+		line := models.LedgerStatementLine{
+			ID:                  e.ID,
+			TransactionID:       e.TransactionID,
+			PostingDate:         e.PostingDate,
+			Remark:              e.Remark,
+			Counterparties:      e.Counterparties,
+			LinkedTransactionID: e.LinkedTransactionID,
+		}
+		if e.Type == "DR" {
+			debits += amount
+			balance += amount
+			line.Debit = e.Amount
+		} else {
+			credits += amount
+			balance -= amount
+			line.Credit = e.Amount
+		}
+		line.Balance = fromCents(balance)
+		lines[i] = line
+	}
+
+	return models.LedgerStatement{
+		AccountID:      aid,
+		AccountName:    name,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		OpeningBalance: opening,
+		Debits:         fromCents(debits),
+		Credits:        fromCents(credits),
+		ClosingBalance: fromCents(balance),
+		Lines:          lines,
+	}
+}
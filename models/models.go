@@ -22,6 +22,42 @@ type TrialEntry struct {
 	Credit          float64 `json:"credit"`
 }
 
+// TrialBalanceCategory holds the accounts of a category with subtotals
+type TrialBalanceCategory struct {
+	Name     string       `json:"name"`
+	Debit    float64      `json:"debit"`
+	Credit   float64      `json:"credit"`
+	Accounts []TrialEntry `json:"accounts"`
+}
+
+// TrialBalanceSubAccount holds the categories of a sub account with subtotals
+type TrialBalanceSubAccount struct {
+	Name       string                 `json:"name"`
+	Debit      float64                `json:"debit"`
+	Credit     float64                `json:"credit"`
+	Categories []TrialBalanceCategory `json:"categories"`
+}
+
+// TrialBalanceMainAccount holds the sub accounts of a main account with
+// subtotals
+type TrialBalanceMainAccount struct {
+	Name        string                   `json:"name"`
+	Debit       float64                  `json:"debit"`
+	Credit      float64                  `json:"credit"`
+	SubAccounts []TrialBalanceSubAccount `json:"sub_accounts"`
+}
+
+// TrialBalanceReport is a trial balance with subtotals and grand totals.
+// Difference is total debits less total credits.
+type TrialBalanceReport struct {
+	PostingDate  string                    `json:"posting_date"`
+	MainAccounts []TrialBalanceMainAccount `json:"main_accounts"`
+	Debit        float64                   `json:"debit"`
+	Credit       float64                   `json:"credit"`
+	Balanced     bool                      `json:"balanced"`
+	Difference   float64                   `json:"difference"`
+}
+
 type ChartOfAccount struct {
 	MainAccountID     int            `json:"main_account_id"`
 	MainAccount       string         `json:"main_account"`
@@ -33,6 +69,94 @@ type ChartOfAccount struct {
 	AccountName       sql.NullString `json:"account_name"`
 }
 
+// ChartNodeRow is a single node of the chart of accounts with a pointer
+// to its parent. Level is one of main_account, sub_account,
+// account_category or account.
+type ChartNodeRow struct {
+	Level       string  `json:"level"`
+	ID          int     `json:"id"`
+	ParentLevel string  `json:"parent_level"`
+	ParentID    int     `json:"parent_id"`
+	AccountID   string  `json:"account_id"`
+	Name        string  `json:"name"`
+	Balance     float64 `json:"balance"`
+}
+
+// ChartNode is a node of the chart of accounts tree. Balance is rolled up
+// from all descendant accounts.
+type ChartNode struct {
+	Level       string       `json:"level"`
+	ID          int          `json:"id"`
+	ParentLevel string       `json:"parent_level"`
+	ParentID    int          `json:"parent_id"`
+	AccountID   string       `json:"account_id"`
+	Name        string       `json:"name"`
+	Balance     float64      `json:"balance"`
+	Children    []*ChartNode `json:"children"`
+}
+
+// ChartLine is a chart of accounts node identified by its level and code
+// as used for import and export. Parents are referenced by level and code.
+type ChartLine struct {
+	Level           string `json:"level"`
+	AccountID       string `json:"account_id"`
+	Name            string `json:"name"`
+	ParentLevel     string `json:"parent_level"`
+	ParentAccountID string `json:"parent_account_id"`
+}
+
+// ChartLineRow is a ChartLine as stored in the database
+type ChartLineRow struct {
+	ID              int    `json:"id"`
+	Level           string `json:"level"`
+	AccountID       string `json:"account_id"`
+	Name            string `json:"name"`
+	ParentLevel     string `json:"parent_level"`
+	ParentAccountID string `json:"parent_account_id"`
+	SubAccountID    int    `json:"sub_account_id"`
+}
+
+// ChartChange is a difference between an imported chart and the existing
+// one. Action is create, update or missing. Missing nodes exist only in
+// the database and are reported but never removed.
+type ChartChange struct {
+	Action             string `json:"action"`
+	Level              string `json:"level"`
+	AccountID          string `json:"account_id"`
+	Name               string `json:"name"`
+	ParentLevel        string `json:"parent_level"`
+	ParentAccountID    string `json:"parent_account_id"`
+	OldName            string `json:"old_name"`
+	OldParentLevel     string `json:"old_parent_level"`
+	OldParentAccountID string `json:"old_parent_account_id"`
+}
+
+// AccountCode maps an account code to the account's internal ID
+type AccountCode struct {
+	AccountID string `json:"account_id"`
+	ID        int    `json:"id"`
+}
+
+// OpeningBalance is the opening debit or credit of an account identified
+// by its code
+type OpeningBalance struct {
+	AccountID string `json:"account_id"`
+	Debit     string `json:"debit"`
+	Credit    string `json:"credit"`
+}
+
+// JournalImportLine is a single line of a batch journal import. Lines
+// sharing a TransactionKey are posted as one transaction and accounts are
+// identified by code.
+type JournalImportLine struct {
+	TransactionKey string `json:"transaction_key"`
+	PostingDate    string `json:"posting_date"`
+	AccountID      string `json:"account_id"`
+	Debit          string `json:"debit"`
+	Credit         string `json:"credit"`
+	Remark         string `json:"remark"`
+}
+
 type PaymentVoucherEntry struct {
 	Account string
 	Amount  string
@@ -45,23 +169,99 @@ type Transaction struct {
 	AccountName   string  `json:"account_name"`
 	Type          string  `json:"type"`
 	Amount        float64 `json:"amount"`
+	ID            int     `json:"id"`
+}
+
+// TransactionPage is a page of the entries of a transaction
+type TransactionPage struct {
+	Entries       []Transaction `json:"entries"`
+	NextCursor    string        `json:"next_cursor"`
+	TotalEstimate int           `json:"total_estimate"`
 }
 
 type LedgerEntry struct {
-	Name          string  `json:"account_name"`
-	TransactionID int     `json:"transaction_id"`
-	PostingDate   string  `json:"posting_date"`
-	Amount        float64 `json:"amount"`
-	Type          string  `json:"type"`
-	Remark        string  `json:"remark"`
+	Name                string  `json:"account_name"`
+	TransactionID       int     `json:"transaction_id"`
+	PostingDate         string  `json:"posting_date"`
+	Amount              float64 `json:"amount"`
+	Type                string  `json:"type"`
+	Remark              string  `json:"remark"`
+	ID                  int     `json:"id"`
+	LinkedTransactionID int     `json:"linked_transaction_id"`
+}
+
+// LedgerStatementEntry is an account transaction together with the names
+// of the accounts on the other side of the transaction
+type LedgerStatementEntry struct {
+	ID                  int     `json:"id"`
+	TransactionID       int     `json:"transaction_id"`
+	PostingDate         string  `json:"posting_date"`
+	Type                string  `json:"type"`
+	Amount              float64 `json:"amount"`
+	Remark              string  `json:"remark"`
+	Counterparties      string  `json:"counterparties"`
+	LinkedTransactionID int     `json:"linked_transaction_id"`
+}
+
+// LedgerStatementLine is a ledger line with the running balance after it.
+// Balances are debit positive.
+type LedgerStatementLine struct {
+	ID                  int     `json:"id"`
+	TransactionID       int     `json:"transaction_id"`
+	PostingDate         string  `json:"posting_date"`
+	Remark              string  `json:"remark"`
+	Counterparties      string  `json:"counterparties"`
+	Debit               float64 `json:"debit"`
+	Credit              float64 `json:"credit"`
+	Balance             float64 `json:"balance"`
+	LinkedTransactionID int     `json:"linked_transaction_id"`
+}
+
+// LedgerStatement is an account ledger for a date range with the balance
+// brought forward from before the range
+type LedgerStatement struct {
+	AccountID      int                   `json:"account_id"`
+	AccountName    string                `json:"account_name"`
+	StartDate      string                `json:"start_date"`
+	EndDate        string                `json:"end_date"`
+	OpeningBalance float64               `json:"opening_balance"`
+	Debits         float64               `json:"debits"`
+	Credits        float64               `json:"credits"`
+	ClosingBalance float64               `json:"closing_balance"`
+	Lines          []LedgerStatementLine `json:"lines"`
+	NextCursor     string                `json:"next_cursor"`
+	TotalEstimate  int                   `json:"total_estimate"`
+}
+
+// LedgerPage is a page of ledger entries. NextCursor is empty on the last
+// page and TotalEstimate is counted when the first page is requested.
+type LedgerPage struct {
+	Entries       []LedgerEntry `json:"entries"`
+	NextCursor    string        `json:"next_cursor"`
+	TotalEstimate int           `json:"total_estimate"`
+}
+
+// PaymentVoucherPage is a page of payment vouchers
+type PaymentVoucherPage struct {
+	PaymentVouchers []PaymentVoucherList `json:"payment_vouchers"`
+	NextCursor      string               `json:"next_cursor"`
+	TotalEstimate   int                  `json:"total_estimate"`
+}
+
+// JEsForAuditPage is a page of journal entries for audit
+type JEsForAuditPage struct {
+	Entries       []JEsForAudit `json:"entries"`
+	NextCursor    string        `json:"next_cursor"`
+	TotalEstimate int           `json:"total_estimate"`
 }
 
 type PaymentVoucherList struct {
-	ID          int       `json:"id"`
-	Datetime    time.Time `json:"date_time"`
-	PostingDate string    `json:"posting_date"`
-	FromAccount string    `json:"from_account"`
-	User        string    `json:"user"`
+	ID             int       `json:"id"`
+	Datetime       time.Time `json:"date_time"`
+	PostingDate    string    `json:"posting_date"`
+	FromAccount    string    `json:"from_account"`
+	User           string    `json:"user"`
+	DocumentNumber string    `json:"document_number"`
 }
 
 type PaymentVoucherSummary struct {
@@ -71,9 +271,69 @@ type PaymentVoucherSummary struct {
 	Remark                sql.NullString          `json:"remark"`
 	Account               sql.NullString          `json:"account"`
 	Datetime              sql.NullString          `json:"datetime"`
+	DocumentNumber        sql.NullString          `json:"document_number"`
 	PaymentVoucherDetails []PaymentVoucherDetails `json:"payment_voucher_details"`
 }
 
+// ReceiptVoucher is money received into ToAccountID and credited to the
+// entries. Cheque fields describe incoming cheques. Receipts from a
+// contract set ContractID to credit its receivables.
+type ReceiptVoucher struct {
+	PostingDate   string `json:"posting_date"`
+	ContractID    string `json:"contract_id"`
+	ToAccountID   string `json:"to_account_id"`
+	Amount        string `json:"amount"`
+	Entries       string `json:"entries"`
+	Remark        string `json:"remark"`
+	ReceiptBookID string `json:"receipt_book_id"`
+	Payer         string `json:"payer"`
+	PaymentMethod string `json:"payment_method"`
+	ChequeNumber  string `json:"cheque_number"`
+	ChequeBank    string `json:"cheque_bank"`
+	ChequeDate    string `json:"cheque_date"`
+}
+
+// ReceiptBook numbers receipts sequentially. NextNumber is the number the
+// next receipt will take.
+type ReceiptBook struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	NextNumber int    `json:"next_number"`
+	Active     bool   `json:"active"`
+}
+
+type ReceiptVoucherList struct {
+	ID            int       `json:"id"`
+	ReceiptNumber string    `json:"receipt_number"`
+	Datetime      time.Time `json:"date_time"`
+	PostingDate   string    `json:"posting_date"`
+	ToAccount     string    `json:"to_account"`
+	Payer         string    `json:"payer"`
+	PaymentMethod string    `json:"payment_method"`
+	User          string    `json:"user"`
+}
+
+// ReceiptVoucherPage is a page of receipt vouchers
+type ReceiptVoucherPage struct {
+	ReceiptVouchers []ReceiptVoucherList `json:"receipt_vouchers"`
+	NextCursor      string               `json:"next_cursor"`
+	TotalEstimate   int                  `json:"total_estimate"`
+}
+
+type ReceiptVoucherSummary struct {
+	ReceiptNumber         string                  `json:"receipt_number"`
+	Payer                 string                  `json:"payer"`
+	PaymentMethod         string                  `json:"payment_method"`
+	ChequeNumber          string                  `json:"cheque_number"`
+	ChequeBank            string                  `json:"cheque_bank"`
+	ChequeDate            string                  `json:"cheque_date"`
+	Remark                string                  `json:"remark"`
+	Account               string                  `json:"account"`
+	Datetime              string                  `json:"datetime"`
+	ReceiptVoucherDetails []PaymentVoucherDetails `json:"receipt_voucher_details"`
+}
+
 type PaymentVoucherDetails struct {
 	AccountID   int     `json:"account_id"`
 	AccountName string  `json:"account_name"`
@@ -82,14 +342,689 @@ type PaymentVoucherDetails struct {
 }
 
 type JEsForAudit struct {
-	Datetime      string  `json:"datetime"`
-	Issuer        string  `json:"issuer"`
+	Datetime            string  `json:"datetime"`
+	Issuer              string  `json:"issuer"`
+	TransactionID       int     `json:"transaction_id"`
+	Account             string  `json:"account"`
+	Type                string  `json:"type"`
+	PostingDate         string  `json:"posting_date"`
+	Amount              float64 `json:"amount"`
+	Remark              string  `json:"remark"`
+	ID                  int     `json:"id"`
+	LinkedTransactionID int     `json:"linked_transaction_id"`
+}
+
+// AuditFilter selects entries for the audit trail. Empty fields do not
+// filter. Account and amount filters select whole transactions having a
+// matching line. Type is voucher, deposit, receipt, bill, opening,
+// reversal or journal. Amounts that are multiples of RoundAmount, 1000
+// when empty, are flagged as round.
+type AuditFilter struct {
+	DateFrom        string `json:"date_from"`
+	DateTo          string `json:"date_to"`
+	PostingDateFrom string `json:"posting_date_from"`
+	PostingDateTo   string `json:"posting_date_to"`
+	UserID          string `json:"user_id"`
+	AccountID       string `json:"account_id"`
+	MinAmount       string `json:"min_amount"`
+	MaxAmount       string `json:"max_amount"`
+	Remark          string `json:"remark"`
+	Type            string `json:"type"`
+	RoundAmount     string `json:"round_amount"`
+	FlaggedOnly     bool   `json:"flagged_only"`
+}
+
+// AuditEntry is a journal entry line with audit exception flags.
+// BackDated is set when the posting date is before the date of entry and
+// SelfApproved when the draft was approved by the user who posted it.
+// Drafts posted by PostDue are never self approved.
+type AuditEntry struct {
+	Datetime            string  `json:"datetime"`
+	Issuer              string  `json:"issuer"`
+	TransactionID       int     `json:"transaction_id"`
+	TransactionType     string  `json:"transaction_type"`
+	Account             string  `json:"account"`
+	Type                string  `json:"type"`
+	PostingDate         string  `json:"posting_date"`
+	Amount              float64 `json:"amount"`
+	Remark              string  `json:"remark"`
+	ID                  int     `json:"id"`
+	WeekendPosting      bool    `json:"weekend_posting"`
+	RoundAmount         bool    `json:"round_amount"`
+	BackDated           bool    `json:"back_dated"`
+	SelfApproved        bool    `json:"self_approved"`
+	LinkedTransactionID int     `json:"linked_transaction_id"`
+}
+
+// AuditTrailPage is a page of the audit trail
+type AuditTrailPage struct {
+	Entries       []AuditEntry `json:"entries"`
+	NextCursor    string       `json:"next_cursor"`
+	TotalEstimate int          `json:"total_estimate"`
+}
+
+// TransactionLogEntry is an entry of the hash-chained transaction log
+type TransactionLogEntry struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	Event         string `json:"event"`
+	ContentHash   string `json:"content_hash"`
+	PreviousHash  string `json:"previous_hash"`
+	Hash          string `json:"hash"`
+}
+
+// IntegrityReport is the result of verifying the transaction log. When
+// Valid is false, LogID and TransactionID identify the first problem found.
+type IntegrityReport struct {
+	Valid         bool   `json:"valid"`
+	Verified      int    `json:"verified"`
+	LogID         int    `json:"log_id"`
+	TransactionID int    `json:"transaction_id"`
+	Problem       string `json:"problem"`
+}
+
+// Change is a change to a single field of a master data record. Old and
+// new values are empty when the field was NULL.
+type Change struct {
+	ID        int    `json:"id"`
+	TableName string `json:"table_name"`
+	RecordID  int    `json:"record_id"`
+	Field     string `json:"field"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	User      string `json:"user"`
+	Datetime  string `json:"datetime"`
+}
+
+// ChangePage is a page of the change history of a record
+type ChangePage struct {
+	Changes       []Change `json:"changes"`
+	NextCursor    string   `json:"next_cursor"`
+	TotalEstimate int      `json:"total_estimate"`
+}
+
+// Draft holds the editable content of a draft posting. Type is journal,
+// payment_voucher or deposit. Entries is the JSON accepted by
+// JournalEntry, PaymentVoucher or Deposit and the payment voucher fields
+// are ignored for journals. Deposits are made to FromAccountID.
+type Draft struct {
+	Type          string `json:"type"`
+	PostingDate   string `json:"posting_date"`
+	Remark        string `json:"remark"`
+	Entries       string `json:"entries"`
+	FromAccountID string `json:"from_account_id"`
+	Amount        string `json:"amount"`
+	DueDate       string `json:"due_date"`
+	CheckNumber   string `json:"check_number"`
+	Payee         string `json:"payee"`
+	ReverseOn     string `json:"reverse_on"`
+}
+
+// DraftDetails is a stored draft with its workflow state. PostedBy is
+// empty for drafts posted by PostDue. Attempts and LastError record failed
+// attempts to post a scheduled draft.
+type DraftDetails struct {
+	ID            int    `json:"id"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	UserID        string `json:"user_id"`
+	PostingDate   string `json:"posting_date"`
+	Remark        string `json:"remark"`
+	Entries       string `json:"entries"`
+	FromAccountID string `json:"from_account_id"`
+	Amount        string `json:"amount"`
+	DueDate       string `json:"due_date"`
+	CheckNumber   string `json:"check_number"`
+	Payee         string `json:"payee"`
+	ApprovedBy    string `json:"approved_by"`
+	PostedBy      string `json:"posted_by"`
+	TransactionID int    `json:"transaction_id"`
+	Datetime      string `json:"datetime"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error"`
+	ReverseOn     string `json:"reverse_on"`
+}
+
+// DraftPage is a page of drafts
+type DraftPage struct {
+	Drafts        []DraftDetails `json:"drafts"`
+	NextCursor    string         `json:"next_cursor"`
+	TotalEstimate int            `json:"total_estimate"`
+}
+
+// PostedDraft is a draft posted by PostDue
+type PostedDraft struct {
+	DraftID       int64 `json:"draft_id"`
+	TransactionID int64 `json:"transaction_id"`
+}
+
+// FailedDraft is a draft PostDue could not post. It stays scheduled and
+// is retried on the next run.
+type FailedDraft struct {
+	DraftID int64  `json:"draft_id"`
+	Error   string `json:"error"`
+}
+
+// PostDueResult summarises a PostDue run
+type PostDueResult struct {
+	Posted []PostedDraft `json:"posted"`
+	Failed []FailedDraft `json:"failed"`
+}
+
+// ApprovalLimit is the largest amount a user may approve. AccountID is 0
+// for the limit that applies to accounts without a specific limit.
+type ApprovalLimit struct {
+	AccountID int     `json:"account_id"`
+	MaxAmount float64 `json:"max_amount"`
+}
+
+// RecurringTemplate generates a journal on every date of its schedule from
+// StartDate until EndDate or until MaxOccurrences dates have passed.
+// Schedule is daily, weekly, monthly or a cron-like "day-of-month month
+// day-of-week" expression. EndDate and MaxOccurrences are optional.
+type RecurringTemplate struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Remark         string `json:"remark"`
+	Schedule       string `json:"schedule"`
+	StartDate      string `json:"start_date"`
+	EndDate        string `json:"end_date"`
+	MaxOccurrences int    `json:"max_occurrences"`
+	UserID         string `json:"user_id"`
+	Active         bool   `json:"active"`
+}
+
+// RecurringTemplatePage is a page of recurring templates
+type RecurringTemplatePage struct {
+	Templates     []RecurringTemplate `json:"templates"`
+	NextCursor    string              `json:"next_cursor"`
+	TotalEstimate int                 `json:"total_estimate"`
+}
+
+// RecurringOccurrence is a transaction, or a draft when postings require
+// approval, generated from a template. Error is set for occurrences that
+// failed to generate.
+type RecurringOccurrence struct {
+	TemplateID    int64  `json:"template_id"`
+	Date          string `json:"date"`
+	TransactionID int64  `json:"transaction_id"`
+	DraftID       int64  `json:"draft_id"`
+	Error         string `json:"error"`
+}
+
+// RecurringOccurrencePage is a page of recurring occurrences
+type RecurringOccurrencePage struct {
+	Occurrences   []RecurringOccurrence `json:"occurrences"`
+	NextCursor    string                `json:"next_cursor"`
+	TotalEstimate int                   `json:"total_estimate"`
+}
+
+// RecurringRunResult summarises a GenerateRecurring run. Failed
+// occurrences are not attempted again until they are retried.
+type RecurringRunResult struct {
+	Generated []RecurringOccurrence `json:"generated"`
+	Failed    []RecurringOccurrence `json:"failed"`
+}
+
+// ChequeBook is a range of cheque numbers for a bank account. NextNumber
+// follows the highest number used from the book.
+type ChequeBook struct {
+	ID          int    `json:"id"`
+	AccountID   int    `json:"account_id"`
+	AccountName string `json:"account_name"`
+	FirstNumber int    `json:"first_number"`
+	LastNumber  int    `json:"last_number"`
+	Used        int    `json:"used"`
+	NextNumber  int    `json:"next_number"`
+	Active      bool   `json:"active"`
+}
+
+// Cheque is a cheque leaf in the cheque register. Leaves cancelled before
+// use have no payment voucher. ReversalTransactionID is the transaction
+// reversing the payment of a stopped, stale or cancelled cheque.
+type Cheque struct {
+	ID                    int     `json:"id"`
+	ChequeBookID          int     `json:"cheque_book_id"`
+	AccountID             int     `json:"account_id"`
+	AccountName           string  `json:"account_name"`
+	Number                int     `json:"number"`
+	PaymentVoucherID      int     `json:"payment_voucher_id"`
+	TransactionID         int     `json:"transaction_id"`
+	PostingDate           string  `json:"posting_date"`
+	DueDate               string  `json:"due_date"`
+	Payee                 string  `json:"payee"`
+	Amount                float64 `json:"amount"`
+	Status                string  `json:"status"`
+	StatusDate            string  `json:"status_date"`
+	ReversalTransactionID int     `json:"reversal_transaction_id"`
+}
+
+// ChequePage is a page of the cheque register
+type ChequePage struct {
+	Cheques       []Cheque `json:"cheques"`
+	NextCursor    string   `json:"next_cursor"`
+	TotalEstimate int      `json:"total_estimate"`
+}
+
+// PrintRecord is a print of a voucher or cheque. Copy 1 is the original.
+// Reason is given for cheque reprints.
+type PrintRecord struct {
+	ID       int    `json:"id"`
+	Document string `json:"document"`
+	RecordID int    `json:"record_id"`
+	Copy     int    `json:"copy"`
+	Reason   string `json:"reason"`
+	UserID   string `json:"user_id"`
+	Datetime string `json:"datetime"`
+}
+
+// PrintRecordPage is a page of the prints of a document
+type PrintRecordPage struct {
+	Prints        []PrintRecord `json:"prints"`
+	NextCursor    string        `json:"next_cursor"`
+	TotalEstimate int           `json:"total_estimate"`
+}
+
+// BankStatement is a bank statement for a period. Amounts are signed from
+// the point of view of the bank account, positive for money received and
+// negative for money paid out.
+type BankStatement struct {
+	AccountID      int                 `json:"account_id"`
+	StartDate      string              `json:"start_date"`
+	EndDate        string              `json:"end_date"`
+	OpeningBalance float64             `json:"opening_balance"`
+	ClosingBalance float64             `json:"closing_balance"`
+	Lines          []BankStatementLine `json:"lines"`
+}
+
+// BankStatementLine is a line of a bank statement. MatchID is zero while
+// the line is unmatched.
+type BankStatementLine struct {
+	ID           int     `json:"id"`
+	Date         string  `json:"date"`
+	Description  string  `json:"description"`
+	Counterparty string  `json:"counterparty"`
+	Reference    string  `json:"reference"`
+	ChequeNumber string  `json:"cheque_number"`
+	Amount       float64 `json:"amount"`
+	MatchID      int     `json:"match_id"`
+}
+
+// BankStatementLinePage is a page of statement lines
+type BankStatementLinePage struct {
+	Lines         []BankStatementLine `json:"lines"`
+	NextCursor    string              `json:"next_cursor"`
+	TotalEstimate int                 `json:"total_estimate"`
+}
+
+// BankStatementDetails is an imported statement, which is also the
+// reconciliation session for its period
+type BankStatementDetails struct {
+	ID             int     `json:"id"`
+	AccountID      int     `json:"account_id"`
+	StartDate      string  `json:"start_date"`
+	EndDate        string  `json:"end_date"`
+	OpeningBalance float64 `json:"opening_balance"`
+	ClosingBalance float64 `json:"closing_balance"`
+	Status         string  `json:"status"`
+	Lines          int     `json:"lines"`
+	Matched        int     `json:"matched"`
+}
+
+// BankStatementPage is a page of imported statements
+type BankStatementPage struct {
+	Statements    []BankStatementDetails `json:"statements"`
+	NextCursor    string                 `json:"next_cursor"`
+	TotalEstimate int                    `json:"total_estimate"`
+}
+
+// BookEntry is a line of a bank account in the books, signed like
+// statement lines
+type BookEntry struct {
+	ID            int     `json:"id"`
+	TransactionID int     `json:"transaction_id"`
+	PostingDate   string  `json:"posting_date"`
+	Amount        float64 `json:"amount"`
+	ChequeNumber  string  `json:"cheque_number"`
+	Remark        string  `json:"remark"`
+}
+
+// BookEntryPage is a page of book entries
+type BookEntryPage struct {
+	Entries       []BookEntry `json:"entries"`
+	NextCursor    string      `json:"next_cursor"`
+	TotalEstimate int         `json:"total_estimate"`
+}
+
+// MatchTolerance sets how far apart a statement line and a book entry may
+// be in days and in amount to be matched automatically
+type MatchTolerance struct {
+	Days   int     `json:"days"`
+	Amount float64 `json:"amount"`
+}
+
+// BankReconciliation reconciles the statement balance with the book
+// balance at the end of a statement period. Difference is zero when the
+// account is reconciled. The opening balance of the first statement of
+// the account is taken as reconciled. OpeningDifference is its difference
+// from the book balance at that time, less the earlier book entries
+// matched since.
+type BankReconciliation struct {
+	StatementID            int                 `json:"statement_id"`
+	AccountID              int                 `json:"account_id"`
+	EndDate                string              `json:"end_date"`
+	StatementBalance       float64             `json:"statement_balance"`
+	DepositsInTransit      []BookEntry         `json:"deposits_in_transit"`
+	TotalDepositsInTransit float64             `json:"total_deposits_in_transit"`
+	UnclearedCheques       []BookEntry         `json:"uncleared_cheques"`
+	TotalUnclearedCheques  float64             `json:"total_uncleared_cheques"`
+	AdjustedBankBalance    float64             `json:"adjusted_bank_balance"`
+	BookBalance            float64             `json:"book_balance"`
+	OpeningDifference      float64             `json:"opening_difference"`
+	UnrecordedItems        []BankStatementLine `json:"unrecorded_items"`
+	TotalUnrecordedItems   float64             `json:"total_unrecorded_items"`
+	AdjustedBookBalance    float64             `json:"adjusted_book_balance"`
+	Difference             float64             `json:"difference"`
+}
+
+// BankRule categorizes imported bank statement lines. A line matches when
+// its description matches the Pattern regular expression, its
+// counterparty contains Counterparty, money moves in Direction and the
+// absolute amount is between MinAmount and MaxAmount. Empty or zero
+// conditions match any line and BankAccountID zero applies the rule to
+// every bank account. Rules are tried in Priority order. The first match
+// posts the line against AccountID when AutoPost is set and proposes it
+// for review otherwise.
+type BankRule struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Priority      int     `json:"priority"`
+	BankAccountID int     `json:"bank_account_id"`
+	Pattern       string  `json:"pattern"`
+	Counterparty  string  `json:"counterparty"`
+	Direction     string  `json:"direction"`
+	MinAmount     float64 `json:"min_amount"`
+	MaxAmount     float64 `json:"max_amount"`
+	AccountID     int     `json:"account_id"`
+	AutoPost      bool    `json:"auto_post"`
+	Active        bool    `json:"active"`
+}
+
+// CategorizedLine is a statement line a bank rule was applied to or that
+// was accepted from the review queue. DraftID is set instead of
+// TransactionID when the posting awaits approval.
+type CategorizedLine struct {
+	LineID        int    `json:"line_id"`
+	RuleID        int    `json:"rule_id"`
+	AccountID     int    `json:"account_id"`
+	TransactionID int64  `json:"transaction_id"`
+	DraftID       int64  `json:"draft_id"`
+	Error         string `json:"error"`
+}
+
+// CategorizeResult lists the statement lines posted and proposed by bank
+// rules. Unmatched counts the lines no rule applied to.
+type CategorizeResult struct {
+	Posted    []CategorizedLine `json:"posted"`
+	Proposed  []CategorizedLine `json:"proposed"`
+	Failed    []CategorizedLine `json:"failed"`
+	Unmatched int               `json:"unmatched"`
+}
+
+// BankReviewItem is an unmatched statement line awaiting review, with the
+// account proposed by a bank rule if there is one. DraftID is set while
+// an accepted line awaits approval.
+type BankReviewItem struct {
+	LineID       int     `json:"line_id"`
+	Date         string  `json:"date"`
+	Description  string  `json:"description"`
+	Counterparty string  `json:"counterparty"`
+	Reference    string  `json:"reference"`
+	Amount       float64 `json:"amount"`
+	RuleID       int     `json:"rule_id"`
+	RuleName     string  `json:"rule_name"`
+	AccountID    int     `json:"account_id"`
+	AccountName  string  `json:"account_name"`
+	DraftID      int64   `json:"draft_id"`
+}
+
+// BankReviewPage is a page of the review queue of a statement
+type BankReviewPage struct {
+	Items         []BankReviewItem `json:"items"`
+	NextCursor    string           `json:"next_cursor"`
+	TotalEstimate int              `json:"total_estimate"`
+}
+
+// ContractBalance is the receivable of a contract. Debit totals the
+// charges and Credit the receipts and credits.
+type ContractBalance struct {
+	ContractID string  `json:"contract_id"`
+	Debit      float64 `json:"debit"`
+	Credit     float64 `json:"credit"`
+	Balance    float64 `json:"balance"`
+}
+
+// ContractBalancePage is a page of contract balances
+type ContractBalancePage struct {
+	Balances      []ContractBalance `json:"balances"`
+	NextCursor    string            `json:"next_cursor"`
+	TotalEstimate int               `json:"total_estimate"`
+}
+
+// ReceivableItem is an entry of a contract on the receivables control
+// account. Charges are debits, receipts and credits are credits.
+// Outstanding is the part of the amount not allocated yet.
+type ReceivableItem struct {
+	EntryID       int     `json:"entry_id"`
 	TransactionID int     `json:"transaction_id"`
-	Account       string  `json:"account"`
+	ContractID    string  `json:"contract_id"`
+	PostingDate   string  `json:"posting_date"`
+	Remark        string  `json:"remark"`
 	Type          string  `json:"type"`
+	Amount        float64 `json:"amount"`
+	Allocated     float64 `json:"allocated"`
+	Outstanding   float64 `json:"outstanding"`
+}
+
+// ReceivableItemPage is a page of receivable items
+type ReceivableItemPage struct {
+	Items         []ReceivableItem `json:"items"`
+	NextCursor    string           `json:"next_cursor"`
+	TotalEstimate int              `json:"total_estimate"`
+}
+
+// ReceivableAllocation settles Amount of a charge with a receipt or credit
+type ReceivableAllocation struct {
+	ID            int     `json:"id"`
+	ChargeEntryID int     `json:"charge_entry_id"`
+	CreditEntryID int     `json:"credit_entry_id"`
+	Amount        float64 `json:"amount"`
+}
+
+// ReceivableAllocationPage is a page of receivable allocations
+type ReceivableAllocationPage struct {
+	Allocations   []ReceivableAllocation `json:"allocations"`
+	NextCursor    string                 `json:"next_cursor"`
+	TotalEstimate int                    `json:"total_estimate"`
+}
+
+// ReceivablesReconciliation compares the receivables control account
+// balance with the sum of the contract balances. Unassigned lists control
+// account entries that belong to no contract.
+type ReceivablesReconciliation struct {
+	Date             string            `json:"date"`
+	AccountID        int               `json:"account_id"`
+	ControlBalance   float64           `json:"control_balance"`
+	SubledgerBalance float64           `json:"subledger_balance"`
+	Difference       float64           `json:"difference"`
+	Reconciled       bool              `json:"reconciled"`
+	Contracts        []ContractBalance `json:"contracts"`
+	Unassigned       []BookEntry       `json:"unassigned"`
+}
+
+// AgingRow spreads what a party owes over the aging buckets by days past
+// due. Summary rows total a party and detail rows show a single item.
+// Unapplied holds receipts and credits not allocated to an item as a
+// negative amount. PartyName is set when the party has a name apart from
+// its key.
+type AgingRow struct {
+	Party         string    `json:"party"`
+	PartyName     string    `json:"party_name"`
+	EntryID       int       `json:"entry_id"`
+	TransactionID int       `json:"transaction_id"`
+	PostingDate   string    `json:"posting_date"`
+	DueDate       string    `json:"due_date"`
+	Remark        string    `json:"remark"`
+	Days          int       `json:"days"`
+	Buckets       []float64 `json:"buckets"`
+	Unapplied     float64   `json:"unapplied"`
+	Total         float64   `json:"total"`
+}
+
+// AgingReport is an aging report as of Date. Buckets names the aging
+// buckets in order and Totals holds their totals.
+type AgingReport struct {
+	Date      string     `json:"date"`
+	Detail    bool       `json:"detail"`
+	Buckets   []string   `json:"buckets"`
+	Rows      []AgingRow `json:"rows"`
+	Totals    []float64  `json:"totals"`
+	Unapplied float64    `json:"unapplied"`
+	Total     float64    `json:"total"`
+}
+
+// Vendor is a supplier paid through accounts payable. Bills fall due
+// PaymentTerms days after posting unless a due date is given.
+type Vendor struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	TaxID             string `json:"tax_id"`
+	BankName          string `json:"bank_name"`
+	BankBranch        string `json:"bank_branch"`
+	BankAccountNumber string `json:"bank_account_number"`
+	PaymentTerms      int    `json:"payment_terms"`
+	Active            bool   `json:"active"`
+}
+
+// VendorPage is a page of the vendor master
+type VendorPage struct {
+	Vendors       []Vendor `json:"vendors"`
+	NextCursor    string   `json:"next_cursor"`
+	TotalEstimate int      `json:"total_estimate"`
+}
+
+// VendorBill is a bill from a vendor credited to the payables control
+// account and debited to the entries, usually expense accounts
+type VendorBill struct {
+	VendorID    int    `json:"vendor_id"`
+	BillNumber  string `json:"bill_number"`
+	PostingDate string `json:"posting_date"`
+	DueDate     string `json:"due_date"`
+	Amount      string `json:"amount"`
+	Entries     string `json:"entries"`
+	Remark      string `json:"remark"`
+}
+
+// VendorPayment pays a vendor from FromAccountID with a payment voucher
+// and settles the allocated bills. Any amount not allocated stays on the
+// vendor as an advance.
+type VendorPayment struct {
+	VendorID      int              `json:"vendor_id"`
+	PostingDate   string           `json:"posting_date"`
+	FromAccountID string           `json:"from_account_id"`
+	Amount        string           `json:"amount"`
+	CheckNumber   string           `json:"check_number"`
+	Remark        string           `json:"remark"`
+	Allocations   []BillAllocation `json:"allocations"`
+}
+
+// BillAllocation settles Amount of a vendor bill
+type BillAllocation struct {
+	BillID int     `json:"bill_id"`
+	Amount float64 `json:"amount"`
+}
+
+// PayableItem is an entry of a vendor on the payables control account.
+// Bills are credits, payments and other debits are debits. Outstanding is
+// the part of the amount not allocated yet.
+type PayableItem struct {
+	EntryID       int     `json:"entry_id"`
+	TransactionID int     `json:"transaction_id"`
+	VendorID      int     `json:"vendor_id"`
+	VendorName    string  `json:"vendor_name"`
+	BillID        int     `json:"bill_id"`
+	BillNumber    string  `json:"bill_number"`
 	PostingDate   string  `json:"posting_date"`
+	DueDate       string  `json:"due_date"`
+	Remark        string  `json:"remark"`
+	Type          string  `json:"type"`
 	Amount        float64 `json:"amount"`
+	Allocated     float64 `json:"allocated"`
+	Outstanding   float64 `json:"outstanding"`
+}
+
+// PayableItemPage is a page of payable items
+type PayableItemPage struct {
+	Items         []PayableItem `json:"items"`
+	NextCursor    string        `json:"next_cursor"`
+	TotalEstimate int           `json:"total_estimate"`
+}
+
+// VendorBalance is what is owed to a vendor. Credit totals the bills and
+// Debit the payments.
+type VendorBalance struct {
+	VendorID int     `json:"vendor_id"`
+	Name     string  `json:"name"`
+	Debit    float64 `json:"debit"`
+	Credit   float64 `json:"credit"`
+	Balance  float64 `json:"balance"`
+}
+
+// VendorBalancePage is a page of vendor balances
+type VendorBalancePage struct {
+	Balances      []VendorBalance `json:"balances"`
+	NextCursor    string          `json:"next_cursor"`
+	TotalEstimate int             `json:"total_estimate"`
+}
+
+// VendorStatementLine is a bill or payment on a vendor statement with the
+// running amount owed
+type VendorStatementLine struct {
+	TransactionID int     `json:"transaction_id"`
+	PostingDate   string  `json:"posting_date"`
+	BillNumber    string  `json:"bill_number"`
 	Remark        string  `json:"remark"`
+	Debit         float64 `json:"debit"`
+	Credit        float64 `json:"credit"`
+	Balance       float64 `json:"balance"`
+	EntryID       int     `json:"entry_id"`
+}
+
+// VendorStatement lists the bills and payments of a vendor between two
+// posting dates
+type VendorStatement struct {
+	Vendor         Vendor                `json:"vendor"`
+	StartDate      string                `json:"start_date"`
+	EndDate        string                `json:"end_date"`
+	OpeningBalance float64               `json:"opening_balance"`
+	Lines          []VendorStatementLine `json:"lines"`
+	ClosingBalance float64               `json:"closing_balance"`
+	NextCursor     string                `json:"next_cursor"`
+	TotalEstimate  int                   `json:"total_estimate"`
+}
+
+// PayablesReconciliation compares the payables control account balance
+// with the sum of the vendor balances. Unassigned lists control account
+// entries that belong to no vendor.
+type PayablesReconciliation struct {
+	Date             string          `json:"date"`
+	AccountID        int             `json:"account_id"`
+	ControlBalance   float64         `json:"control_balance"`
+	SubledgerBalance float64         `json:"subledger_balance"`
+	Difference       float64         `json:"difference"`
+	Reconciled       bool            `json:"reconciled"`
+	Vendors          []VendorBalance `json:"vendors"`
+	Unassigned       []BookEntry     `json:"unassigned"`
 }
 
 type AccountBalanceForReports struct {
@@ -0,0 +1,142 @@
+package scribe
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Document types numbered in gapless series. The type is also the prefix
+// of the document number.
+const (
+	DocumentPaymentVoucher = "PV"
+	DocumentDeposit        = "DP"
+	DocumentJournal        = "JV"
+	DocumentVendorBill     = "VB"
+)
+
+// FiscalYearStartMonth is the first month of the financial year
+const FiscalYearStartMonth = time.April
+
+// fiscalYear returns the calendar year in which the financial year of the
+// date starts
+func fiscalYear(date time.Time) int {
+	if date.Month() < FiscalYearStartMonth {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// formatDocumentNumber formats a number such as PV/2026/00123, with the
+// branch after the prefix when set
+func formatDocumentNumber(documentType, branch string, year, number int) string {
+	parts := []string{documentType}
+	if branch != "" {
+		parts = append(parts, branch)
+	}
+	parts = append(parts, fmt.Sprint(year), fmt.Sprintf("%05d", number))
+	return strings.Join(parts, "/")
+}
+
+// allocateDocumentNumber assigns the next number of the series for the
+// document type, branch and fiscal year of the posting date to a
+// transaction. The series row stays locked until the transaction ends and
+// a rolled back transaction releases its number, so series have no gaps.
+// Each fiscal year starts a new series from one.
+func allocateDocumentNumber(tx *sql.Tx, documentType, branch, postingDate string, tid int64) (string, error) {
+	date, err := time.Parse("2006-01-02", postingDate)
+	if err != nil {
+		return "", fmt.Errorf("invalid posting date")
+	}
+	year := fiscalYear(date)
+
+	_, err = tx.Exec(queries.CreateNumberSeries, documentType, branch, year)
+	if err != nil {
+		return "", err
+	}
+
+	var sid int64
+	var next int
+	err = tx.QueryRow(queries.NumberSeriesForUpdate, documentType, branch, year).Scan(&sid, &next)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "number_series",
+			Columns:   []string{"next_number"},
+			Vals:      []interface{}{next + 1},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{fmt.Sprint(sid)},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	number := formatDocumentNumber(documentType, branch, year, next)
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "document_number",
+		Columns:   []string{"transaction_id", "number_series_id", "number", "document_number"},
+		Vals:      []interface{}{tid, sid, next, number},
+		Tx:        tx,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return number, nil
+}
+
+// moveDocumentNumber gives the document number of a transaction to the
+// transaction replacing it, when the number belongs to the series the
+// replacement would be numbered from. It reports whether a number was
+// moved.
+func moveDocumentNumber(tx *sql.Tx, documentType, branch, postingDate string, from, to int64) (bool, error) {
+	date, err := time.Parse("2006-01-02", postingDate)
+	if err != nil {
+		return false, fmt.Errorf("invalid posting date")
+	}
+
+	r, err := tx.Exec(queries.MoveDocumentNumber, to, from, documentType, branch, fiscalYear(date))
+	if err != nil {
+		return false, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// DocumentNumber returns the document number of a transaction, empty when
+// it was not numbered
+func (m *AccountModel) DocumentNumber(tid int) (string, error) {
+	var number string
+	err := m.DB.QueryRow(queries.DocumentNumber, tid).Scan(&number)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return number, nil
+}
+
+// TransactionByDocumentNumber returns the transaction ID of a document
+// number
+func (m *AccountModel) TransactionByDocumentNumber(number string) (int64, error) {
+	var tid int64
+	err := m.DB.QueryRow(queries.TransactionByDocumentNumber, number).Scan(&tid)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
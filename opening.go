@@ -0,0 +1,185 @@
+package scribe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// OpeningBalances posts opening balances as a single transaction dated on
+// the posting date. Opening balances are exempt from the financial year
+// restriction on posting dates. Any previously posted opening balances are
+// replaced in the same database transaction. The new transaction keeps
+// their journal voucher number when it is in the series of the posting
+// date, and takes the next number of that series otherwise.
+func (m *AccountModel) OpeningBalances(userID, postingDate string, balances []models.OpeningBalance) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+	if _, err := time.Parse("2006-01-02", postingDate); err != nil {
+		return 0, errors.New("invalid posting date")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	accounts, err := accountsByCode(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	journalEntries, err := openingJournalEntries(accounts, balances)
+	if err != nil {
+		return 0, err
+	}
+
+	var previous []int64
+	rows, err := tx.Query(queries.OpeningBalanceTransactions)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var tid int64
+		if err = rows.Scan(&tid); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		previous = append(previous, tid)
+	}
+	rows.Close()
+
+	tid, err := mysequel.Insert(mysequel.Table{
+		TableName: "transaction",
+		Columns:   []string{"user_id", "datetime", "posting_date", "remark"},
+		Vals:      []interface{}{userID, time.Now().Format("2006-01-02 15:04:05"), postingDate, "Opening balances"},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "opening_balance",
+		Columns:   []string{"transaction_id"},
+		Vals:      []interface{}{tid},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	numbered := false
+	for _, ptid := range previous {
+		if !numbered {
+			numbered, err = moveDocumentNumber(tx, DocumentJournal, m.Branch, postingDate, ptid, tid)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if err = appendTransactionLog(tx, ptid, LogVoid); err != nil {
+			return 0, err
+		}
+		for _, q := range []string{queries.DeleteDocumentNumber, queries.DeleteAccountTransactions, queries.DeleteOpeningBalance, queries.DeleteTransaction} {
+			if _, err = tx.Exec(q, ptid); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if !numbered {
+		_, err = allocateDocumentNumber(tx, DocumentJournal, m.Branch, postingDate, tid)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// ImportOpeningBalances reads opening balances from a CSV file with
+// account_id, debit and credit columns and posts them with OpeningBalances
+func (m *AccountModel) ImportOpeningBalances(userID, postingDate string, r io.Reader) (int64, error) {
+	records, err := readCSV(r, []string{"account_id", "debit", "credit"})
+	if err != nil {
+		return 0, err
+	}
+
+	balances := make([]models.OpeningBalance, len(records))
+	for i, rec := range records {
+		balances[i] = models.OpeningBalance{
+			AccountID: rec.fields["account_id"],
+			Debit:     rec.fields["debit"],
+			Credit:    rec.fields["credit"],
+		}
+	}
+
+	return m.OpeningBalances(userID, postingDate, balances)
+}
+
+// openingJournalEntries validates every opening balance and converts them
+// to journal entries. All row errors are reported together.
+func openingJournalEntries(accounts map[string]int, balances []models.OpeningBalance) ([]models.JournalEntry, error) {
+	var ierr ImportError
+	var debits, credits int64
+	var journalEntries []models.JournalEntry
+	seen := make(map[string]int, len(balances))
+
+	for i, b := range balances {
+		row := i + 1
+		aid, ok := accounts[b.AccountID]
+		if !ok {
+			ierr.add(row, "account %s does not exist", b.AccountID)
+		}
+		if prev, dup := seen[b.AccountID]; dup {
+			ierr.add(row, "account %s already given on row %d", b.AccountID, prev)
+		}
+		seen[b.AccountID] = row
+
+		debit, credit, valid := ierr.debitCredit(row, b.Debit, b.Credit)
+		if !ok || !valid || (debit == 0 && credit == 0) {
+			continue
+		}
+
+		debits += debit
+		credits += credit
+		entry := models.JournalEntry{Account: strconv.Itoa(aid)}
+		if debit != 0 {
+			entry.Debit = formatAmount(debit)
+		} else {
+			entry.Credit = formatAmount(credit)
+		}
+		journalEntries = append(journalEntries, entry)
+	}
+
+	if err := ierr.err(); err != nil {
+		return nil, err
+	}
+	if len(journalEntries) == 0 {
+		return nil, errors.New("no opening balances given")
+	}
+	if debits != credits {
+		return nil, fmt.Errorf("opening balances do not balance: debits %s, credits %s", formatAmount(debits), formatAmount(credits))
+	}
+
+	return journalEntries, nil
+}
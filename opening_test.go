@@ -0,0 +1,57 @@
+package scribe
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+func TestOpeningBalancesRerun(t *testing.T) {
+	balances := []models.OpeningBalance{{AccountID: "1000", Debit: "100"}, {AccountID: "2000", Credit: "100"}}
+	rows := map[string][][]driver.Value{
+		queries.AccountCodes:               {{"1000", int64(1)}, {"2000", int64(2)}},
+		queries.OpeningBalanceTransactions: {{int64(7)}},
+		queries.TransactionLogLock:         {{int64(1)}},
+		queries.NumberSeriesForUpdate:      {{int64(3), int64(5)}},
+	}
+
+	t.Run("keeps the number", func(t *testing.T) {
+		db, stub := newStubDB(t, rows)
+		m := &AccountModel{DB: db, Branch: "HO"}
+
+		tid, err := m.OpeningBalances("1", "2024-04-01", balances)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moved := stub.committedTo(queries.MoveDocumentNumber)
+		if len(moved) != 1 || moved[0].args[0] != tid || moved[0].args[1] != int64(7) {
+			t.Fatalf("got moves %v, want the number of 7 moved to %d", moved, tid)
+		}
+		if got := stub.committedTo(queries.CreateNumberSeries); len(got) != 0 {
+			t.Errorf("allocated a new number: %v", got)
+		}
+		deleted := stub.committedTo(queries.DeleteTransaction)
+		if len(deleted) != 1 || deleted[0].args[0] != int64(7) {
+			t.Errorf("got deletes %v, want transaction 7 deleted", deleted)
+		}
+	})
+
+	t.Run("numbers from another series", func(t *testing.T) {
+		db, stub := newStubDB(t, rows)
+		stub.affected = map[string]int64{queries.MoveDocumentNumber: 0}
+		m := &AccountModel{DB: db, Branch: "HO"}
+
+		if _, err := m.OpeningBalances("1", "2025-04-01", balances); err != nil {
+			t.Fatal(err)
+		}
+		deleted := stub.committedTo(queries.DeleteDocumentNumber)
+		if len(deleted) != 1 || deleted[0].args[0] != int64(7) {
+			t.Errorf("got deletes %v, want the number of 7 deleted", deleted)
+		}
+		if got := stub.committedTo(queries.CreateNumberSeries); len(got) != 1 {
+			t.Errorf("got %d allocations, want 1", len(got))
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package scribe
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Keyset pages are offered for the lists that grow with posting volume as
+// a ...Page variant of the list method, and AuditTrail is paged only.
+// Financial reports, the chart of accounts, bank rules, cheque and receipt
+// books, cheque gaps and aging reports are returned whole.
+
+// Page sizes used when a page size is not given or is too large
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// pageCursor is the decoded form of an opaque page cursor. Keys are the
+// sort keys of the last row on the previous page and Total is the row
+// count taken when the first page was requested.
+type pageCursor struct {
+	Keys  []string `json:"k"`
+	Total int      `json:"t"`
+}
+
+func encodeCursor(total int, keys ...interface{}) string {
+	c := pageCursor{Keys: make([]string, len(keys)), Total: total}
+	for i, k := range keys {
+		c.Keys[i] = fmt.Sprint(k)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes a cursor with n sort keys. An empty cursor requests
+// the first page.
+func decodeCursor(cursor string, n int) (pageCursor, error) {
+	var c pageCursor
+	if cursor == "" {
+		return c, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(b, &c); err != nil || len(c.Keys) != n {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// first reports whether the cursor requests the first page
+func (c pageCursor) first() bool {
+	return len(c.Keys) == 0
+}
+
+// args returns the placeholder values for a keyset condition written as
+// (? IS NULL OR (key, ...) > (?, ...)) with n keys
+func (c pageCursor) args(n int) []interface{} {
+	args := make([]interface{}, n+1)
+	if c.first() {
+		return args
+	}
+	args[0] = c.Keys[0]
+	for i, k := range c.Keys {
+		args[i+1] = k
+	}
+	return args
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
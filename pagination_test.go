@@ -0,0 +1,63 @@
+package scribe
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ssrdive/scribe/queries"
+)
+
+func TestTransactionPage(t *testing.T) {
+	db, _ := newStubDB(t, map[string][][]driver.Value{
+		queries.TransactionCount: {{int64(3)}},
+		queries.TransactionPage: {
+			{int64(7), int64(1000), int64(1), "Cash", "DR", "10.00", int64(11)},
+			{int64(7), int64(2000), int64(2), "Sales", "CR", "4.00", int64(12)},
+			{int64(7), int64(2001), int64(3), "Tax", "CR", "6.00", int64(13)},
+		},
+	})
+	m := &AccountModel{DB: db}
+
+	page, err := m.TransactionPage(7, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Entries) != 2 || page.TotalEstimate != 3 {
+		t.Fatalf("got %d entries of %d, want 2 of 3", len(page.Entries), page.TotalEstimate)
+	}
+	c, err := decodeCursor(page.NextCursor, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Keys[0] != "12" || c.Total != 3 {
+		t.Errorf("got cursor %+v, want key 12 and total 3", c)
+	}
+
+	if _, err := m.TransactionPage(7, "not a cursor", 2); err == nil {
+		t.Error("invalid cursor: expected an error")
+	}
+}
+
+func TestVendorStatementPage(t *testing.T) {
+	db, _ := newStubDB(t, map[string][][]driver.Value{
+		queries.Vendors:              {{int64(5), "Acme", "", "", "", "", int64(30), true}},
+		queries.VendorBalanceThrough: {{"150.00"}},
+		queries.VendorStatementLinesPage: {
+			{int64(21), "2024-02-01", "B-2", "", "0", "50.00", "0", int64(31)},
+			{int64(22), "2024-02-03", "", "", "120.00", "0", "0", int64(32)},
+		},
+	})
+	m := &AccountModel{DB: db, PayableAccountID: 9}
+
+	cursor := encodeCursor(4, "2024-01-20", 30)
+	res, err := m.VendorStatementPage(5, "2024-01-01", "2024-03-31", cursor, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OpeningBalance != 150 || res.ClosingBalance != 80 || res.TotalEstimate != 4 || res.NextCursor != "" {
+		t.Errorf("got opening %v, closing %v, total %d, next %q", res.OpeningBalance, res.ClosingBalance, res.TotalEstimate, res.NextCursor)
+	}
+	if res.Lines[0].Balance != 200 || res.Lines[1].Balance != 80 {
+		t.Errorf("got running balances %v and %v, want 200 and 80", res.Lines[0].Balance, res.Lines[1].Balance)
+	}
+}
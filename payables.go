@@ -0,0 +1,747 @@
+package scribe
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/export"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// errNoPayableAccount is returned by the payables subledger when no
+// control account is set
+var errNoPayableAccount = errors.New("payable control account is not set")
+
+func validateVendor(v models.Vendor) error {
+	if strings.TrimSpace(v.Name) == "" {
+		return errors.New("vendor name is required")
+	}
+	if v.PaymentTerms < 0 {
+		return errors.New("payment terms cannot be negative")
+	}
+	return nil
+}
+
+// CreateVendor adds a vendor to the vendor master
+func (m *AccountModel) CreateVendor(userID string, v models.Vendor) (int64, error) {
+	if err := validateVendor(v); err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	vt := mysequel.Table{
+		TableName: "vendor",
+		Columns:   []string{"name", "tax_id", "bank_name", "bank_branch", "bank_account_number", "payment_terms", "active", "user_id", "datetime"},
+		Vals:      []interface{}{v.Name, v.TaxID, v.BankName, v.BankBranch, v.BankAccountNumber, v.PaymentTerms, 1, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	}
+	vid, err := mysequel.Insert(vt)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, vt.TableName, vid, vt.Columns, vt.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return vid, nil
+}
+
+// UpdateVendor updates a vendor and records the changes
+func (m *AccountModel) UpdateVendor(userID string, id int64, v models.Vendor) error {
+	if err := validateVendor(v); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	active := 0
+	if v.Active {
+		active = 1
+	}
+	err = updateRecord(tx, userID, "vendor", id, []string{"name", "tax_id", "bank_name", "bank_branch", "bank_account_number", "payment_terms", "active"},
+		[]interface{}{v.Name, v.TaxID, v.BankName, v.BankBranch, v.BankAccountNumber, v.PaymentTerms, active})
+	return err
+}
+
+// Vendors returns the vendor master
+func (m *AccountModel) Vendors() ([]models.Vendor, error) {
+	var res []models.Vendor
+	err := mysequel.QueryToStructs(&res, m.DB, queries.Vendors, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// VendorsPage returns a page of the vendor master in name order
+func (m *AccountModel) VendorsPage(cursor string, limit int) (models.VendorPage, error) {
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.VendorPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.VendorsCount).Scan(&c.Total)
+		if err != nil {
+			return models.VendorPage{}, err
+		}
+	}
+
+	var res []models.Vendor
+	err = mysequel.QueryToStructs(&res, m.DB, queries.VendorsPage, append(c.args(2), size+1)...)
+	if err != nil {
+		return models.VendorPage{}, err
+	}
+
+	page := models.VendorPage{Vendors: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Vendors = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.Name, last.ID)
+	}
+	return page, nil
+}
+
+// vendor reads a vendor by ID
+func vendor(q mysequel.QueryRunner, id int) (models.Vendor, error) {
+	var res []models.Vendor
+	err := mysequel.QueryToStructs(&res, q, queries.Vendors, id, id)
+	if err != nil {
+		return models.Vendor{}, err
+	}
+	if len(res) == 0 {
+		return models.Vendor{}, errors.New("vendor not found")
+	}
+
+	return res[0], nil
+}
+
+// Vendor returns a vendor
+func (m *AccountModel) Vendor(id int) (models.Vendor, error) {
+	return vendor(m.DB, id)
+}
+
+// VendorBill posts a vendor bill to the payables control account. The due
+// date defaults to the payment terms of the vendor. A vendor cannot have
+// two bills with the same number.
+func (m *AccountModel) VendorBill(userID string, b models.VendorBill) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+	if m.PayableAccountID == 0 {
+		return 0, errNoPayableAccount
+	}
+
+	var entries []models.PaymentVoucherEntry
+	if err := json.Unmarshal([]byte(b.Entries), &entries); err != nil {
+		return 0, errors.New("invalid bill entries")
+	}
+	if strings.TrimSpace(b.BillNumber) == "" {
+		return 0, errors.New("bill number is required")
+	}
+	amount, err := parseAmount(b.Amount)
+	if err != nil {
+		return 0, err
+	}
+	var debits int64
+	for _, e := range entries {
+		c, err := parseAmount(e.Amount)
+		if err != nil {
+			return 0, err
+		}
+		debits += c
+	}
+	if amount == 0 || debits != amount {
+		return 0, errors.New("bill entries do not add up to the amount")
+	}
+	err = validatePostingDate(b.PostingDate)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := vendor(m.DB, b.VendorID)
+	if err != nil {
+		return 0, err
+	}
+	if !v.Active {
+		return 0, errors.New("vendor is inactive")
+	}
+	posted, _ := time.Parse("2006-01-02", b.PostingDate)
+	if b.DueDate == "" {
+		b.DueDate = posted.AddDate(0, 0, v.PaymentTerms).Format("2006-01-02")
+	}
+	due, err := time.Parse("2006-01-02", b.DueDate)
+	if err != nil || due.Before(posted) {
+		return 0, errors.New("invalid due date")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	tid, err := CreateTransaction(tx, userID, b.PostingDate, "", b.Remark)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = allocateDocumentNumber(tx, DocumentVendorBill, m.Branch, b.PostingDate, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	vb := mysequel.Table{
+		TableName: "vendor_bill",
+		Columns:   []string{"transaction_id", "vendor_id", "bill_number", "due_date", "amount"},
+		Vals:      []interface{}{tid, b.VendorID, b.BillNumber, b.DueDate, formatAmount(amount)},
+		Tx:        tx,
+	}
+	bid, err := mysequel.Insert(vb)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, vb.TableName, bid, vb.Columns, vb.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "vendor_transaction",
+		Columns:   []string{"transaction_id", "vendor_id"},
+		Vals:      []interface{}{tid, b.VendorID},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "account_transaction",
+		Columns:   []string{"transaction_id", "account_id", "type", "amount"},
+		Vals:      []interface{}{tid, m.PayableAccountID, "CR", formatAmount(amount)},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "account_transaction",
+			Columns:   []string{"transaction_id", "account_id", "type", "amount"},
+			Vals:      []interface{}{tid, entry.Account, "DR", entry.Amount},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = appendTransactionLog(tx, tid, LogPost)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// openPayables reads the open payable items of a vendor, or of every
+// vendor when vendorID is zero, as they stood on the posting date
+func openPayables(q mysequel.QueryRunner, accountID, vendorID int, postingDate string) ([]models.PayableItem, error) {
+	var v interface{}
+	if vendorID != 0 {
+		v = vendorID
+	}
+
+	var res []models.PayableItem
+	err := mysequel.QueryToStructs(&res, q, queries.OpenPayableItems, postingDate, postingDate, accountID, postingDate, v, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// OpenPayables returns the bills and payments of a vendor that were not
+// fully allocated on the posting date. All vendors are included when
+// vendorID is zero.
+func (m *AccountModel) OpenPayables(vendorID int, postingDate string) ([]models.PayableItem, error) {
+	if m.PayableAccountID == 0 {
+		return nil, errNoPayableAccount
+	}
+
+	return openPayables(m.DB, m.PayableAccountID, vendorID, postingDate)
+}
+
+// OpenPayablesPage returns a page of OpenPayables in vendor and due date
+// order
+func (m *AccountModel) OpenPayablesPage(vendorID int, postingDate, cursor string, limit int) (models.PayableItemPage, error) {
+	if m.PayableAccountID == 0 {
+		return models.PayableItemPage{}, errNoPayableAccount
+	}
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.PayableItemPage{}, err
+	}
+	size := pageSize(limit)
+	var v interface{}
+	if vendorID != 0 {
+		v = vendorID
+	}
+	filter := []interface{}{postingDate, postingDate, m.PayableAccountID, postingDate, v, v}
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.OpenPayableItemsCount, filter...).Scan(&c.Total)
+		if err != nil {
+			return models.PayableItemPage{}, err
+		}
+	}
+
+	var res []models.PayableItem
+	args := append(filter, c.args(3)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.OpenPayableItemsPage, append(args, size+1)...)
+	if err != nil {
+		return models.PayableItemPage{}, err
+	}
+
+	page := models.PayableItemPage{Items: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Items = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.VendorID, last.DueDate, last.EntryID)
+	}
+	return page, nil
+}
+
+// lockVendorPayables locks the control account entries of a vendor so
+// that its allocations change one at a time, and returns its current open
+// items
+func lockVendorPayables(tx *sql.Tx, accountID, vendorID int) ([]models.PayableItem, error) {
+	rows, err := tx.Query(queries.LockVendorPayables, accountID, vendorID)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return openPayables(tx, accountID, vendorID, openEnded)
+}
+
+// settleBills allocates up to available of a debit entry to open bills of
+// the vendor whose items were locked
+func settleBills(tx *sql.Tx, userID string, items []models.PayableItem, debitEntryID int, available int64, allocations []models.BillAllocation) error {
+	bills := make(map[int]models.PayableItem)
+	for _, i := range items {
+		if i.Type == "CR" && i.BillID != 0 {
+			bills[i.BillID] = i
+		}
+	}
+
+	for _, a := range allocations {
+		bill, ok := bills[a.BillID]
+		if !ok {
+			return errors.New("allocation is not to an open bill of the vendor")
+		}
+		amount := toCents(a.Amount)
+		if amount <= 0 {
+			return errors.New("allocation amount must be positive")
+		}
+		if amount > toCents(bill.Outstanding) || amount > available {
+			return errors.New("allocation exceeds the outstanding amount")
+		}
+		bill.Outstanding = fromCents(toCents(bill.Outstanding) - amount)
+		bills[a.BillID] = bill
+		available -= amount
+
+		_, err := mysequel.Insert(mysequel.Table{
+			TableName: "payable_allocation",
+			Columns:   []string{"bill_entry_id", "debit_entry_id", "amount", "user_id", "datetime"},
+			Vals:      []interface{}{bill.EntryID, debitEntryID, formatAmount(amount), userID, time.Now().Format("2006-01-02 15:04:05")},
+			Tx:        tx,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VendorPayment pays a vendor with a payment voucher debiting the payables
+// control account and allocates the payment to the vendor's bills
+func (m *AccountModel) VendorPayment(userID string, p models.VendorPayment) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+	if m.PayableAccountID == 0 {
+		return 0, errNoPayableAccount
+	}
+	amount, err := parseAmount(p.Amount)
+	if err != nil {
+		return 0, err
+	}
+	if amount == 0 {
+		return 0, errors.New("payment amount is required")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	v, err := vendor(tx, p.VendorID)
+	if err != nil {
+		return 0, err
+	}
+
+	items, err := lockVendorPayables(tx, m.PayableAccountID, p.VendorID)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := json.Marshal([]models.PaymentVoucherEntry{{Account: strconv.Itoa(m.PayableAccountID), Amount: formatAmount(amount)}})
+	if err != nil {
+		return 0, err
+	}
+	tid, err := issuePaymentVoucher(tx, m.Branch, userID, p.PostingDate, p.FromAccountID, formatAmount(amount), string(entries), p.Remark, "", p.CheckNumber, v.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "vendor_transaction",
+		Columns:   []string{"transaction_id", "vendor_id"},
+		Vals:      []interface{}{tid, p.VendorID},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var entryID int
+	err = tx.QueryRow(queries.TransactionAccountEntry, tid, m.PayableAccountID).Scan(&entryID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = settleBills(tx, userID, items, entryID, amount, p.Allocations)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// AllocateVendorPayment allocates the unallocated part of a payment or
+// other debit on the payables control account to bills of the same
+// vendor
+func (m *AccountModel) AllocateVendorPayment(userID string, debitEntryID int, allocations []models.BillAllocation) error {
+	if m.PayableAccountID == 0 {
+		return errNoPayableAccount
+	}
+	if len(allocations) == 0 {
+		return errors.New("no allocations")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var vendorID int
+	err = tx.QueryRow(queries.PayableEntryVendor, debitEntryID, m.PayableAccountID).Scan(&vendorID)
+	if err == sql.ErrNoRows {
+		err = errors.New("entry does not belong to a vendor")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	items, err := lockVendorPayables(tx, m.PayableAccountID, vendorID)
+	if err != nil {
+		return err
+	}
+	var available int64 = -1
+	for _, i := range items {
+		if i.EntryID == debitEntryID && i.Type == "DR" {
+			available = toCents(i.Outstanding)
+		}
+	}
+	if available < 0 {
+		err = errors.New("entry is not an open payment")
+		return err
+	}
+
+	err = settleBills(tx, userID, items, debitEntryID, available, allocations)
+	return err
+}
+
+// VendorBalances returns what is owed to every vendor on the posting date
+func (m *AccountModel) VendorBalances(postingDate string) ([]models.VendorBalance, error) {
+	if m.PayableAccountID == 0 {
+		return nil, errNoPayableAccount
+	}
+
+	var res []models.VendorBalance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.VendorBalances, m.PayableAccountID, postingDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// VendorBalancesPage returns a page of VendorBalances in vendor name order
+func (m *AccountModel) VendorBalancesPage(postingDate, cursor string, limit int) (models.VendorBalancePage, error) {
+	if m.PayableAccountID == 0 {
+		return models.VendorBalancePage{}, errNoPayableAccount
+	}
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.VendorBalancePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.VendorBalancesCount, m.PayableAccountID, postingDate).Scan(&c.Total)
+		if err != nil {
+			return models.VendorBalancePage{}, err
+		}
+	}
+
+	var res []models.VendorBalance
+	args := append([]interface{}{m.PayableAccountID, postingDate}, c.args(2)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.VendorBalancesPage, append(args, size+1)...)
+	if err != nil {
+		return models.VendorBalancePage{}, err
+	}
+
+	page := models.VendorBalancePage{Balances: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Balances = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.Name, last.VendorID)
+	}
+	return page, nil
+}
+
+// VendorStatement returns the bills and payments of a vendor between two
+// posting dates with the balance brought forward and a running balance
+func (m *AccountModel) VendorStatement(vendorID int, startDate, endDate string) (models.VendorStatement, error) {
+	res := models.VendorStatement{StartDate: startDate, EndDate: endDate, Lines: []models.VendorStatementLine{}}
+	if m.PayableAccountID == 0 {
+		return res, errNoPayableAccount
+	}
+
+	v, err := vendor(m.DB, vendorID)
+	if err != nil {
+		return res, err
+	}
+	res.Vendor = v
+
+	err = m.DB.QueryRow(queries.VendorOpeningBalance, m.PayableAccountID, vendorID, startDate).Scan(&res.OpeningBalance)
+	if err != nil {
+		return res, err
+	}
+
+	err = mysequel.QueryToStructs(&res.Lines, m.DB, queries.VendorStatementLines, m.PayableAccountID, vendorID, startDate, endDate)
+	if err != nil {
+		return res, err
+	}
+
+	vendorRunningBalances(&res)
+	return res, nil
+}
+
+// vendorRunningBalances sets the running balance of every line of a
+// vendor statement and its closing balance from the opening balance
+func vendorRunningBalances(res *models.VendorStatement) {
+	balance := toCents(res.OpeningBalance)
+	for i := range res.Lines {
+		l := &res.Lines[i]
+		balance += toCents(l.Credit) - toCents(l.Debit)
+		l.Balance = fromCents(balance)
+	}
+	res.ClosingBalance = fromCents(balance)
+}
+
+// VendorStatementPage returns a page of VendorStatement. The opening
+// balance is the balance brought forward to the first line of the page.
+func (m *AccountModel) VendorStatementPage(vendorID int, startDate, endDate, cursor string, limit int) (models.VendorStatement, error) {
+	res := models.VendorStatement{StartDate: startDate, EndDate: endDate, Lines: []models.VendorStatementLine{}}
+	if m.PayableAccountID == 0 {
+		return res, errNoPayableAccount
+	}
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return res, err
+	}
+	size := pageSize(limit)
+
+	v, err := vendor(m.DB, vendorID)
+	if err != nil {
+		return res, err
+	}
+	res.Vendor = v
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.VendorOpeningBalance, m.PayableAccountID, vendorID, startDate).Scan(&res.OpeningBalance)
+		if err == nil {
+			err = m.DB.QueryRow(queries.VendorStatementLinesCount, m.PayableAccountID, vendorID, startDate, endDate).Scan(&c.Total)
+		}
+	} else {
+		err = m.DB.QueryRow(queries.VendorBalanceThrough, m.PayableAccountID, vendorID, c.Keys[0], c.Keys[1]).Scan(&res.OpeningBalance)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	args := append([]interface{}{m.PayableAccountID, vendorID, startDate, endDate}, c.args(2)...)
+	err = mysequel.QueryToStructs(&res.Lines, m.DB, queries.VendorStatementLinesPage, append(args, size+1)...)
+	if err != nil {
+		return res, err
+	}
+	if len(res.Lines) > size {
+		res.Lines = res.Lines[:size]
+		last := res.Lines[size-1]
+		res.NextCursor = encodeCursor(c.Total, last.PostingDate, last.EntryID)
+	}
+	res.TotalEstimate = c.Total
+
+	vendorRunningBalances(&res)
+	return res, nil
+}
+
+// PayablesAging ages the open bills of every vendor by due date as of a
+// date into the buckets of the day limits, DefaultAgingLimits when nil.
+// Rows are keyed by vendor ID with the vendor name as party name.
+// Payments not allocated to a bill are shown as unapplied.
+func (m *AccountModel) PayablesAging(date string, limits []int, detail bool) (models.AgingReport, error) {
+	if limits == nil {
+		limits = DefaultAgingLimits
+	}
+
+	open, err := m.OpenPayables(0, date)
+	if err != nil {
+		return models.AgingReport{}, err
+	}
+
+	items := make([]agingItem, len(open))
+	for i, o := range open {
+		outstanding := toCents(o.Outstanding)
+		if o.Type == "DR" {
+			outstanding = -outstanding
+		}
+		remark := o.Remark
+		if o.BillNumber != "" {
+			remark = strings.TrimSpace(o.BillNumber + " " + remark)
+		}
+		items[i] = agingItem{party: strconv.Itoa(o.VendorID), name: o.VendorName, entryID: o.EntryID, transactionID: o.TransactionID, postingDate: o.PostingDate,
+			dueDate: o.DueDate, remark: remark, outstanding: outstanding}
+	}
+
+	return agingReport(date, limits, detail, items)
+}
+
+// ExportPayablesAging writes the payables aging report by vendor
+func (m *AccountModel) ExportPayablesAging(w export.Writer, meta export.Metadata, date string, limits []int, detail bool) error {
+	report, err := m.PayablesAging(date, limits, detail)
+	if err != nil {
+		return err
+	}
+
+	return writeAging(w, meta, "Vendor ID", "Vendor", report)
+}
+
+// PayablesReconciliation checks that the vendor balances add up to the
+// balance of the payable control account in the trial balance on the
+// posting date
+func (m *AccountModel) PayablesReconciliation(postingDate string) (models.PayablesReconciliation, error) {
+	res := models.PayablesReconciliation{Date: postingDate, AccountID: m.PayableAccountID}
+	if m.PayableAccountID == 0 {
+		return res, errNoPayableAccount
+	}
+
+	trial, err := m.TrialBalance(postingDate)
+	if err != nil {
+		return res, err
+	}
+	var control int64
+	for _, e := range trial {
+		if e.ID == m.PayableAccountID {
+			control = toCents(e.Credit) - toCents(e.Debit)
+		}
+	}
+
+	res.Vendors, err = m.VendorBalances(postingDate)
+	if err != nil {
+		return res, err
+	}
+	var subledger int64
+	for _, v := range res.Vendors {
+		subledger += toCents(v.Balance)
+	}
+
+	err = mysequel.QueryToStructs(&res.Unassigned, m.DB, queries.UnassignedPayableEntries, m.PayableAccountID, postingDate)
+	if err != nil {
+		return res, err
+	}
+
+	res.ControlBalance = fromCents(control)
+	res.SubledgerBalance = fromCents(subledger)
+	res.Difference = fromCents(control - subledger)
+	res.Reconciled = control == subledger
+	return res, nil
+}
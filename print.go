@@ -0,0 +1,202 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/printing"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Printed documents tracked in the print log
+const (
+	PrintPaymentVoucher = "payment_voucher"
+	PrintCheque         = "cheque"
+)
+
+// logPrint records a print of a document and returns its copy number,
+// 1 for the original. Cheques are reprinted only with a reason.
+func logPrint(tx *sql.Tx, userID, document, reason string, id int) (int, error) {
+	var prints int
+	err := tx.QueryRow(queries.PrintCount, document, id).Scan(&prints)
+	if err != nil {
+		return 0, err
+	}
+	if document == PrintCheque && prints > 0 && reason == "" {
+		return 0, errors.New("cheque is already printed and needs a reason to reprint")
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "print_log",
+		Columns:   []string{"document", "record_id", "copy", "reason", "user_id", "datetime"},
+		Vals:      []interface{}{document, id, prints + 1, reason, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return prints + 1, nil
+}
+
+// printTx logs a print and renders the document within a transaction so
+// that failed prints are not counted
+func (m *AccountModel) printTx(userID, document, reason string, id int, render func(copyNumber int) error) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	copyNumber, err := logPrint(tx, userID, document, reason, id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = render(copyNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	return copyNumber, nil
+}
+
+// PrintPaymentVoucher writes a payment voucher as PDF and returns the copy
+// number. Reprints are marked as such on the voucher.
+func (m *AccountModel) PrintPaymentVoucher(w io.Writer, userID string, pid int, letterhead printing.Letterhead, currency printing.Currency) (int, error) {
+	summary, err := m.PaymentVoucherDetails(pid)
+	if err != nil {
+		return 0, err
+	}
+	if len(summary.PaymentVoucherDetails) == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	return m.printTx(userID, PrintPaymentVoucher, "", pid, func(copyNumber int) error {
+		return printing.WriteVoucher(w, printing.Voucher{
+			ID:         pid,
+			Letterhead: letterhead,
+			Summary:    summary,
+			Currency:   currency,
+			Copy:       printing.Copy{Number: copyNumber, PrintedBy: userID, PrintedAt: time.Now().Format("2006-01-02 15:04")},
+		})
+	})
+}
+
+// PrintCheque writes the cheque of a payment voucher as PDF using the
+// layout and returns the copy number. The cheque is dated on the due date
+// of post-dated vouchers. Cheques in the register can only be printed
+// while issued, and only once; use ReprintCheque to print them again.
+func (m *AccountModel) PrintCheque(w io.Writer, userID string, pid int, layout printing.ChequeLayout, currency printing.Currency) (int, error) {
+	return m.printCheque(w, userID, "", pid, layout, currency)
+}
+
+// ReprintCheque prints a cheque again, such as after a printer jam, and
+// records the reason in the print log
+func (m *AccountModel) ReprintCheque(w io.Writer, userID, reason string, pid int, layout printing.ChequeLayout, currency printing.Currency) (int, error) {
+	if reason == "" {
+		return 0, errors.New("reprint reason is required")
+	}
+	return m.printCheque(w, userID, reason, pid, layout, currency)
+}
+
+func (m *AccountModel) printCheque(w io.Writer, userID, reason string, pid int, layout printing.ChequeLayout, currency printing.Currency) (int, error) {
+	summary, err := m.PaymentVoucherDetails(pid)
+	if err != nil {
+		return 0, err
+	}
+	if len(summary.PaymentVoucherDetails) == 0 {
+		return 0, sql.ErrNoRows
+	}
+	if summary.CheckNumber.String == "" {
+		return 0, errors.New("payment voucher has no cheque")
+	}
+	if summary.Payee.String == "" {
+		return 0, errors.New("payment voucher has no payee")
+	}
+
+	var number int
+	var status string
+	err = m.DB.QueryRow(queries.ChequeStatusForVoucher, pid).Scan(&number, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	if err == nil && status != ChequeIssued {
+		return 0, fmt.Errorf("cheque %d is %s", number, status)
+	}
+
+	var total float64
+	for _, d := range summary.PaymentVoucherDetails {
+		total += d.Amount
+	}
+	// Dates may be scanned with a time part depending on the driver
+	date := summary.PaymentVoucherDetails[0].PostingDate
+	if summary.DueDate.String != "" {
+		date = summary.DueDate.String
+	}
+	if len(date) > 10 {
+		date = date[:10]
+	}
+
+	return m.printTx(userID, PrintCheque, reason, pid, func(int) error {
+		return printing.WriteCheque(w, layout, printing.Cheque{
+			Date:     date,
+			Payee:    summary.Payee.String,
+			Amount:   total,
+			Currency: currency,
+		})
+	})
+}
+
+// PrintHistory returns the prints of a document, oldest first
+func (m *AccountModel) PrintHistory(document string, id int) ([]models.PrintRecord, error) {
+	var res []models.PrintRecord
+	err := mysequel.QueryToStructs(&res, m.DB, queries.PrintHistory, document, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PrintHistoryPage returns a page of the prints of a document, oldest
+// first
+func (m *AccountModel) PrintHistoryPage(document string, id int, cursor string, limit int) (models.PrintRecordPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.PrintRecordPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.PrintHistoryCount, document, id).Scan(&c.Total)
+		if err != nil {
+			return models.PrintRecordPage{}, err
+		}
+	}
+
+	var res []models.PrintRecord
+	args := append([]interface{}{document, id}, c.args(1)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.PrintHistoryPage, append(args, size+1)...)
+	if err != nil {
+		return models.PrintRecordPage{}, err
+	}
+
+	page := models.PrintRecordPage{Prints: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Prints = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
@@ -0,0 +1,195 @@
+package printing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ssrdive/scribe/internal/pdf"
+)
+
+// Cheque fields filled from the payment. Other fields of a layout print
+// their fixed Text, such as an account payee crossing.
+const (
+	FieldDate        = "date"
+	FieldPayee       = "payee"
+	FieldAmount      = "amount"
+	FieldAmountWords = "amount_words"
+)
+
+// mm converts millimetres to points
+const mm = 72 / 25.4
+
+// Field places a value on the cheque. Positions are in millimetres from
+// the top left corner of the leaf to the baseline of the text. Text wider
+// than Width wraps onto lines LineSpacing apart starting at ContinueX, or
+// at X when ContinueX is not set.
+// Spacing spreads characters at a fixed pitch to fill printed boxes, as
+// for dates.
+type Field struct {
+	X           float64 `json:"x"`
+	Y           float64 `json:"y"`
+	Size        float64 `json:"size"`
+	Width       float64 `json:"width"`
+	ContinueX   float64 `json:"continue_x"`
+	LineSpacing float64 `json:"line_spacing"`
+	Spacing     float64 `json:"spacing"`
+	Align       string  `json:"align"`
+	Text        string  `json:"text"`
+}
+
+// ChequeLayout describes where fields are printed on a cheque leaf of the
+// given size in millimetres. DateFormat is a Go time layout and Guard is
+// written either side of the amount in figures.
+type ChequeLayout struct {
+	Width      float64          `json:"width"`
+	Height     float64          `json:"height"`
+	DateFormat string           `json:"date_format"`
+	Guard      string           `json:"guard"`
+	Fields     map[string]Field `json:"fields"`
+}
+
+// DefaultChequeLayout fits a 178 by 89 millimetre leaf with date boxes at
+// the top right
+var DefaultChequeLayout = ChequeLayout{
+	Width:      178,
+	Height:     89,
+	DateFormat: "02012006",
+	Guard:      "**",
+	Fields: map[string]Field{
+		FieldDate:        {X: 136, Y: 12, Size: 11, Spacing: 5},
+		FieldPayee:       {X: 22, Y: 28, Size: 11, Width: 140},
+		FieldAmountWords: {X: 30, Y: 38, Size: 10, Width: 100, ContinueX: 10, LineSpacing: 8},
+		FieldAmount:      {X: 170, Y: 46, Size: 11, Align: "right"},
+		"crossing":       {X: 8, Y: 10, Size: 9, Text: "A/C PAYEE ONLY"},
+	},
+}
+
+// Validate checks the layout dimensions and fields
+func (l ChequeLayout) Validate() error {
+	if l.Width <= 0 || l.Height <= 0 {
+		return errors.New("cheque layout must have a width and height")
+	}
+	for name, f := range l.Fields {
+		switch name {
+		case FieldDate, FieldPayee, FieldAmount, FieldAmountWords:
+		default:
+			if f.Text == "" {
+				return fmt.Errorf("field %s has no text", name)
+			}
+		}
+		if f.X < 0 || f.X > l.Width || f.Y < 0 || f.Y > l.Height {
+			return fmt.Errorf("field %s is outside the cheque", name)
+		}
+		if f.Align != "" && f.Align != "left" && f.Align != "right" {
+			return fmt.Errorf("field %s has invalid alignment %s", name, f.Align)
+		}
+	}
+	return nil
+}
+
+// ParseChequeLayout reads a cheque layout from JSON
+func ParseChequeLayout(r io.Reader) (ChequeLayout, error) {
+	var l ChequeLayout
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return ChequeLayout{}, err
+	}
+	return l, l.Validate()
+}
+
+// Cheque is a cheque to print. Date is formatted YYYY-MM-DD.
+type Cheque struct {
+	Date     string
+	Payee    string
+	Amount   float64
+	Currency Currency
+}
+
+// WriteCheque writes a cheque as a single page PDF the size of the leaf,
+// to be printed onto pre-printed cheque stock
+func WriteCheque(w io.Writer, layout ChequeLayout, c Cheque) error {
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+	date, err := time.Parse("2006-01-02", c.Date)
+	if err != nil {
+		return errors.New("invalid cheque date")
+	}
+	if cents(c.Amount) <= 0 {
+		return errors.New("cheque amount must be positive")
+	}
+
+	words, err := AmountInWords(c.Amount, c.Currency)
+	if err != nil {
+		return err
+	}
+
+	format := layout.DateFormat
+	if format == "" {
+		format = "2006-01-02"
+	}
+	values := map[string]string{
+		FieldDate:        date.Format(format),
+		FieldPayee:       c.Payee,
+		FieldAmount:      layout.Guard + FormatAmount(c.Amount) + layout.Guard,
+		FieldAmountWords: words,
+	}
+
+	// Fields are written in name order so that output is reproducible
+	names := make([]string, 0, len(layout.Fields))
+	for name := range layout.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	height := layout.Height * mm
+	var page bytes.Buffer
+	for _, name := range names {
+		f := layout.Fields[name]
+		v := f.Text
+		if v == "" {
+			v = values[name]
+		}
+		size := f.Size
+		if size <= 0 {
+			size = 10
+		}
+		x, y := f.X*mm, height-f.Y*mm
+
+		switch {
+		case f.Spacing > 0:
+			for i, r := range []rune(v) {
+				page.WriteString(pdf.Text(pdf.Regular, size, x+float64(i)*f.Spacing*mm, y, string(r)))
+			}
+		case f.Width > 0:
+			spacing := f.LineSpacing * mm
+			if spacing <= 0 {
+				spacing = size * 1.4
+			}
+			continueX := f.ContinueX
+			if continueX <= 0 {
+				continueX = f.X
+			}
+			for i, l := range wrap(v, size, f.Width*mm) {
+				if i > 0 {
+					x, y = continueX*mm, y-spacing
+				}
+				page.WriteString(pdf.Text(pdf.Regular, size, x, y, l))
+			}
+		case f.Align == "right":
+			page.WriteString(pdf.Text(pdf.Regular, size, x-pdf.TextWidth(v, size), y, v))
+		default:
+			page.WriteString(pdf.Text(pdf.Regular, size, x, y, v))
+		}
+	}
+
+	pw := pdf.NewWriter(w)
+	if err := pw.Page(layout.Width*mm, height, page.String()); err != nil {
+		return err
+	}
+	return pw.Close()
+}
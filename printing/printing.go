@@ -0,0 +1,88 @@
+// Package printing renders payment vouchers and cheques as PDF documents
+// ready to print
+package printing
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency names the major and minor units used when writing amounts in
+// words. Plural names default to the singular when empty.
+type Currency struct {
+	Major       string `json:"major"`
+	MajorPlural string `json:"major_plural"`
+	Minor       string `json:"minor"`
+	MinorPlural string `json:"minor_plural"`
+}
+
+// Rupees is the default currency
+var Rupees = Currency{Major: "Rupee", MajorPlural: "Rupees", Minor: "Cent", MinorPlural: "Cents"}
+
+func (c Currency) major(n int64) string {
+	if n != 1 && c.MajorPlural != "" {
+		return c.MajorPlural
+	}
+	return c.Major
+}
+
+func (c Currency) minor(n int64) string {
+	if n != 1 && c.MinorPlural != "" {
+		return c.MinorPlural
+	}
+	return c.Minor
+}
+
+// Letterhead is printed at the top of vouchers
+type Letterhead struct {
+	Company string `json:"company"`
+	Address string `json:"address"`
+}
+
+// Copy describes which print of a document is being produced. Reprints
+// are marked so that they cannot be mistaken for the original.
+type Copy struct {
+	Number    int
+	PrintedBy string
+	PrintedAt string
+}
+
+// label returns the reprint marking, empty for the original
+func (c Copy) label() string {
+	if c.Number <= 1 {
+		return ""
+	}
+	l := "REPRINT " + strconv.Itoa(c.Number-1)
+	if c.PrintedBy != "" {
+		l += " by " + c.PrintedBy
+	}
+	if c.PrintedAt != "" {
+		l += " on " + c.PrintedAt
+	}
+	return l
+}
+
+// cents converts an amount to cents
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FormatAmount formats an amount with thousands separators and two
+// decimals
+func FormatAmount(amount float64) string {
+	c := cents(amount)
+	sign := ""
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	whole := strconv.FormatInt(c/100, 10)
+	var b strings.Builder
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String() + "." + strconv.FormatInt(c%100/10, 10) + strconv.FormatInt(c%10, 10)
+}
@@ -0,0 +1,207 @@
+package printing
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ssrdive/scribe/internal/pdf"
+	"github.com/ssrdive/scribe/models"
+)
+
+// Portrait A4 voucher geometry in points
+const (
+	voucherWidth      = 595
+	voucherHeight     = 842
+	voucherMargin     = 48
+	voucherFontSize   = 10
+	voucherLineHeight = 15
+)
+
+// Voucher is a payment voucher to print
+type Voucher struct {
+	ID         int
+	Letterhead Letterhead
+	Summary    models.PaymentVoucherSummary
+	Currency   Currency
+	Copy       Copy
+}
+
+// wrap splits s into lines no wider than width
+func wrap(s string, size, width float64) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		if line != "" && pdf.TextWidth(line+" "+word, size) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type voucherPage struct {
+	w    *pdf.Writer
+	page *bytes.Buffer
+	y    float64
+}
+
+func (p *voucherPage) text(font string, size, x float64, s string) {
+	p.page.WriteString(pdf.Text(font, size, x, p.y, s))
+}
+
+func (p *voucherPage) right(font string, size, x float64, s string) {
+	p.text(font, size, x-pdf.TextWidth(s, size), s)
+}
+
+func (p *voucherPage) rule() {
+	p.page.WriteString(pdf.Line(0.5, voucherMargin, p.y, voucherWidth-voucherMargin, p.y))
+}
+
+// fits reports whether n more lines fit above the bottom margin
+func (p *voucherPage) fits(n int) bool {
+	return p.y-float64((n-1)*voucherLineHeight) >= voucherMargin
+}
+
+// newPage writes out the current page and starts the next
+func (p *voucherPage) newPage() error {
+	if err := p.w.Page(voucherWidth, voucherHeight, p.page.String()); err != nil {
+		return err
+	}
+	p.page = &bytes.Buffer{}
+	p.y = voucherHeight - voucherMargin - voucherLineHeight
+	return nil
+}
+
+// WriteVoucher writes a payment voucher as a portrait A4 PDF with its
+// lines, the total in figures and words and signature blocks. The voucher
+// is identified by its document number, or by ID when it has none.
+// Reprints are marked on every page.
+func WriteVoucher(w io.Writer, v Voucher) error {
+	p := &voucherPage{w: pdf.NewWriter(w), page: &bytes.Buffer{}, y: voucherHeight - voucherMargin - 14}
+	s := v.Summary
+
+	if v.Letterhead.Company != "" {
+		p.text(pdf.Bold, 14, voucherMargin, v.Letterhead.Company)
+		p.y -= 16
+	}
+	if v.Letterhead.Address != "" {
+		p.text(pdf.Regular, voucherFontSize, voucherMargin, v.Letterhead.Address)
+		p.y -= voucherLineHeight
+	}
+	p.y -= 10
+	p.text(pdf.Bold, 12, voucherMargin, "PAYMENT VOUCHER")
+	if l := v.Copy.label(); l != "" {
+		p.right(pdf.Bold, 12, voucherWidth-voucherMargin, l)
+	}
+	p.y -= 24
+
+	var postingDate string
+	var total float64
+	for _, d := range s.PaymentVoucherDetails {
+		postingDate = d.PostingDate
+		total += d.Amount
+	}
+
+	voucherNo := s.DocumentNumber.String
+	if voucherNo == "" {
+		voucherNo = strconv.Itoa(v.ID)
+	}
+	info := [][2]string{
+		{"Voucher No", voucherNo},
+		{"Date", postingDate},
+		{"Payee", s.Payee.String},
+		{"Paid From", s.Account.String},
+		{"Cheque No", s.CheckNumber.String},
+		{"Due Date", s.DueDate.String},
+	}
+	for _, i := range info {
+		if i[1] == "" {
+			continue
+		}
+		p.text(pdf.Bold, voucherFontSize, voucherMargin, i[0])
+		p.text(pdf.Regular, voucherFontSize, voucherMargin+90, i[1])
+		p.y -= voucherLineHeight
+	}
+	if s.Remark.String != "" {
+		p.text(pdf.Bold, voucherFontSize, voucherMargin, "Remark")
+		for _, l := range wrap(s.Remark.String, voucherFontSize, voucherWidth-2*voucherMargin-90) {
+			p.text(pdf.Regular, voucherFontSize, voucherMargin+90, l)
+			p.y -= voucherLineHeight
+		}
+	}
+	p.y -= 10
+
+	amountX := float64(voucherWidth - voucherMargin)
+	header := func() {
+		p.text(pdf.Bold, voucherFontSize, voucherMargin, "Account")
+		p.text(pdf.Bold, voucherFontSize, voucherMargin+70, "Account Name")
+		p.right(pdf.Bold, voucherFontSize, amountX, "Amount")
+		p.y -= 5
+		p.rule()
+		p.y -= voucherLineHeight
+	}
+	header()
+	for _, d := range s.PaymentVoucherDetails {
+		if !p.fits(1) {
+			if err := p.newPage(); err != nil {
+				return err
+			}
+			if l := v.Copy.label(); l != "" {
+				p.right(pdf.Bold, 12, voucherWidth-voucherMargin, l)
+				p.y -= 24
+			}
+			header()
+		}
+		p.text(pdf.Regular, voucherFontSize, voucherMargin, strconv.Itoa(d.AccountID))
+		p.text(pdf.Regular, voucherFontSize, voucherMargin+70, pdf.Truncate(d.AccountName, voucherFontSize, amountX-voucherMargin-170))
+		p.right(pdf.Regular, voucherFontSize, amountX, FormatAmount(d.Amount))
+		p.y -= voucherLineHeight
+	}
+
+	amountWords, err := AmountInWords(total, v.Currency)
+	if err != nil {
+		return err
+	}
+	words := wrap(amountWords, voucherFontSize, voucherWidth-2*voucherMargin-90)
+	if !p.fits(len(words) + 8) {
+		if err := p.newPage(); err != nil {
+			return err
+		}
+	}
+	p.y += voucherLineHeight - 5
+	p.rule()
+	p.y -= voucherLineHeight
+	p.text(pdf.Bold, voucherFontSize, voucherMargin+70, "Total")
+	p.right(pdf.Bold, voucherFontSize, amountX, FormatAmount(total))
+	p.y -= 2 * voucherLineHeight
+
+	p.text(pdf.Bold, voucherFontSize, voucherMargin, "Amount in words")
+	for _, l := range words {
+		p.text(pdf.Regular, voucherFontSize, voucherMargin+90, l)
+		p.y -= voucherLineHeight
+	}
+
+	// Signature blocks across the foot of the last page
+	p.y -= 4 * voucherLineHeight
+	signatures := []string{"Prepared By", "Checked By", "Approved By", "Received By"}
+	width := float64(voucherWidth-2*voucherMargin) / float64(len(signatures))
+	for i, sig := range signatures {
+		x := voucherMargin + float64(i)*width
+		p.page.WriteString(pdf.Line(0.5, x, p.y+voucherLineHeight, x+width-12, p.y+voucherLineHeight))
+		p.text(pdf.Regular, voucherFontSize, x, sig)
+	}
+
+	if err := p.w.Page(voucherWidth, voucherHeight, p.page.String()); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
@@ -0,0 +1,123 @@
+package printing
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var ones = []string{"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten",
+	"Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+
+var tens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+
+var scales = []string{"", "Thousand", "Million", "Billion", "Trillion", "Quadrillion"}
+
+// MaxNumberInWords is the largest number that can be written in words
+const MaxNumberInWords = 999999999999999999
+
+// errTooLarge is returned for numbers beyond MaxNumberInWords
+var errTooLarge = errors.New("number is too large to write in words")
+
+// hundreds writes a number below one thousand
+func hundreds(n int64) []string {
+	var w []string
+	if n >= 100 {
+		w = append(w, ones[n/100], "Hundred")
+		n %= 100
+	}
+	if n >= 20 {
+		t := tens[n/10]
+		if n%10 > 0 {
+			t += "-" + ones[n%10]
+		}
+		w = append(w, t)
+	} else if n > 0 {
+		w = append(w, ones[n])
+	}
+	return w
+}
+
+// NumberInWords writes a whole number in English words using the short
+// scale, such as "One Million Two Hundred Thousand". Numbers beyond
+// MaxNumberInWords either way are an error.
+func NumberInWords(n int64) (string, error) {
+	if n > MaxNumberInWords || n < -MaxNumberInWords {
+		return "", errTooLarge
+	}
+	if n == 0 {
+		return "Zero", nil
+	}
+	if n < 0 {
+		w, err := NumberInWords(-n)
+		if err != nil {
+			return "", err
+		}
+		return "Minus " + w, nil
+	}
+
+	var groups []int64
+	for ; n > 0; n /= 1000 {
+		groups = append(groups, n%1000)
+	}
+	var w []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		w = append(w, hundreds(groups[i])...)
+		if scales[i] != "" {
+			w = append(w, scales[i])
+		}
+	}
+	return strings.Join(w, " "), nil
+}
+
+// AmountInWords writes an amount in English words in the given currency,
+// such as "One Thousand Two Hundred Rupees and Fifty Cents Only". Minor
+// units are written as a fraction when the currency has no minor name.
+func AmountInWords(amount float64, c Currency) (string, error) {
+	if math.IsNaN(amount) || math.Abs(amount) > MaxNumberInWords/100 {
+		return "", errTooLarge
+	}
+	total := cents(amount)
+	if total < 0 {
+		w, err := AmountInWords(-amount, c)
+		if err != nil {
+			return "", err
+		}
+		return "Minus " + w, nil
+	}
+	major, minor := total/100, total%100
+
+	var w []string
+	if major > 0 || minor == 0 {
+		words, err := NumberInWords(major)
+		if err != nil {
+			return "", err
+		}
+		w = append(w, words)
+		if name := c.major(major); name != "" {
+			w = append(w, name)
+		}
+	}
+	if minor > 0 {
+		if len(w) > 0 {
+			w = append(w, "and")
+		}
+		if c.Minor == "" {
+			w = append(w, fraction(minor))
+		} else {
+			words, err := NumberInWords(minor)
+			if err != nil {
+				return "", err
+			}
+			w = append(w, words, c.minor(minor))
+		}
+	}
+	return strings.Join(append(w, "Only"), " "), nil
+}
+
+func fraction(minor int64) string {
+	return string(rune('0'+minor/10)) + string(rune('0'+minor%10)) + "/100"
+}
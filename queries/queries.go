@@ -40,35 +40,137 @@ const ChartOfAccounts = `
 	RIGHT JOIN main_account MA ON MA.id = SA.main_account_id
 `
 
+const ChartTree = `
+	SELECT level, id, parent_level, parent_id, account_id, name, balance FROM (
+		(SELECT 'main_account' AS level, MA.id, '' AS parent_level, 0 AS parent_id, COALESCE(MA.account_id, '') AS account_id, MA.name, 0 AS balance, 1 AS depth
+		FROM main_account MA)
+		UNION ALL
+		(SELECT 'sub_account', SA.id, 'main_account', SA.main_account_id, COALESCE(SA.account_id, ''), SA.name, 0, 2
+		FROM sub_account SA)
+		UNION ALL
+		(SELECT 'account_category', AC.id, IF(AC.parent_id IS NULL, 'sub_account', 'account_category'), COALESCE(AC.parent_id, AC.sub_account_id), COALESCE(AC.account_id, ''), AC.name, 0, 3
+		FROM account_category AC)
+		UNION ALL
+		(SELECT 'account', A.id, 'account_category', A.account_category_id, COALESCE(A.account_id, ''), A.name, COALESCE(AT.debit-AT.credit, 0), 4
+		FROM account A
+		LEFT JOIN (
+			SELECT AT.account_id, SUM(CASE WHEN AT.type = "DR" THEN AT.amount ELSE 0 END) AS debit, SUM(CASE WHEN AT.type = "CR" THEN AT.amount ELSE 0 END) AS credit
+			FROM account_transaction AT
+			LEFT JOIN transaction T ON AT.transaction_id = T.id
+			WHERE T.posting_date <= ?
+			GROUP BY AT.account_id
+		) AT ON AT.account_id = A.id)
+	) C
+	ORDER BY depth, account_id, id
+`
+
+const ChartLines = `
+	SELECT id, level, account_id, name, parent_level, parent_account_id, sub_account_id FROM (
+		(SELECT MA.id, 'main_account' AS level, COALESCE(MA.account_id, '') AS account_id, MA.name, '' AS parent_level, '' AS parent_account_id, 0 AS sub_account_id, 1 AS depth
+		FROM main_account MA)
+		UNION ALL
+		(SELECT SA.id, 'sub_account', COALESCE(SA.account_id, ''), SA.name, 'main_account', COALESCE(MA.account_id, ''), 0, 2
+		FROM sub_account SA
+		LEFT JOIN main_account MA ON MA.id = SA.main_account_id)
+		UNION ALL
+		(SELECT AC.id, 'account_category', COALESCE(AC.account_id, ''), AC.name, IF(P.id IS NULL, 'sub_account', 'account_category'), COALESCE(P.account_id, SA.account_id, ''), AC.sub_account_id, 3
+		FROM account_category AC
+		LEFT JOIN account_category P ON P.id = AC.parent_id
+		LEFT JOIN sub_account SA ON SA.id = AC.sub_account_id)
+		UNION ALL
+		(SELECT A.id, 'account', COALESCE(A.account_id, ''), A.name, 'account_category', COALESCE(AC.account_id, ''), 0, 4
+		FROM account A
+		LEFT JOIN account_category AC ON AC.id = A.account_category_id)
+	) C
+	ORDER BY depth, account_id, id
+`
+
+const CategorySubAccount = `
+	SELECT sub_account_id FROM account_category WHERE id = ?
+`
+
 const Transaction = `
-	SELECT AT.transaction_id, A.account_id, A.id AS account_id2, A.name AS account_name, AT.type, AT.amount
+	SELECT AT.transaction_id, A.account_id, A.id AS account_id2, A.name AS account_name, AT.type, AT.amount, AT.id
 	FROM account_transaction AT
 	LEFT JOIN account A ON A.id = AT.account_id
 	WHERE AT.transaction_id = ?
 `
 
+const TransactionPage = `
+	SELECT AT.transaction_id, A.account_id, A.id AS account_id2, A.name AS account_name, AT.type, AT.amount, AT.id
+	FROM account_transaction AT
+	LEFT JOIN account A ON A.id = AT.account_id
+	WHERE AT.transaction_id = ? AND (? IS NULL OR AT.id > ?)
+	ORDER BY AT.id
+	LIMIT ?
+`
+
+const TransactionCount = `
+	SELECT COUNT(*) FROM account_transaction WHERE transaction_id = ?
+`
+
 const AccountLedger = `
-	SELECT A.name, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') as posting_date, AT.amount, AT.type, T.remark
+	SELECT A.name, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') as posting_date, AT.amount, AT.type, T.remark, AT.id,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
 	FROM account_transaction AT
 	LEFT JOIN account A ON A.id = AT.account_id
 	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
 	WHERE AT.account_id = ?
+	ORDER BY T.posting_date, AT.transaction_id, AT.id
+`
+
+const AccountLedgerPage = `
+	SELECT A.name, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') as posting_date, AT.amount, AT.type, T.remark, AT.id,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
+	FROM account_transaction AT
+	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
+	WHERE AT.account_id = ? AND (? IS NULL OR (T.posting_date, AT.transaction_id, AT.id) > (?, ?, ?))
+	ORDER BY T.posting_date, AT.transaction_id, AT.id
+	LIMIT ?
+`
+
+const AccountLedgerCount = `
+	SELECT COUNT(*) FROM account_transaction WHERE account_id = ?
 `
 
 const PaymentVouchers = `
-	SELECT PV.id, T.datetime, T.posting_date, A.name AS from_account, U.name AS user
+	SELECT PV.id, T.datetime, T.posting_date, A.name AS from_account, U.name AS user, COALESCE(DN.document_number, '') AS document_number
 	FROM payment_voucher PV
 	LEFT JOIN transaction T ON T.id = PV.transaction_id
+	LEFT JOIN document_number DN ON DN.transaction_id = T.id
 	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'CR'
 	LEFT JOIN account A ON A.id = AT.account_id
 	LEFT JOIN user U ON T.user_id = U.id
 	ORDER BY T.datetime DESC
 `
 
+const PaymentVouchersPage = `
+	SELECT PV.id, T.datetime, T.posting_date, A.name AS from_account, U.name AS user, COALESCE(DN.document_number, '') AS document_number
+	FROM payment_voucher PV
+	LEFT JOIN transaction T ON T.id = PV.transaction_id
+	LEFT JOIN document_number DN ON DN.transaction_id = T.id
+	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'CR'
+	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN user U ON T.user_id = U.id
+	WHERE (? IS NULL OR PV.id < ?)
+	ORDER BY PV.id DESC
+	LIMIT ?
+`
+
+const PaymentVouchersCount = `
+	SELECT COUNT(*) FROM payment_voucher
+`
+
 const PaymentVoucherCheckDetails = `
-	SELECT PV.due_date, PV.check_number, PV.payee, T.remark, A.name AS account_name, T.datetime
+	SELECT PV.due_date, PV.check_number, PV.payee, T.remark, A.name AS account_name, T.datetime, DN.document_number
 	FROM payment_voucher PV
 	LEFT JOIN transaction T ON T.id = PV.transaction_id
+	LEFT JOIN document_number DN ON DN.transaction_id = T.id
 	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'CR'
 	LEFT JOIN account A ON A.id = AT.account_id
 	WHERE PV.id = ?
@@ -84,15 +186,40 @@ const PaymentVoucherDetails = `
 `
 
 const JournalEntriesForAudit = `
-	SELECT T.datetime, U.name AS issuer, AT.transaction_id, A.name AS account, AT.type, T.posting_date, AT.amount,  T.remark
+	SELECT T.datetime, U.name AS issuer, AT.transaction_id, A.name AS account, AT.type, T.posting_date, AT.amount,  T.remark, AT.id,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
 	FROM transaction T
 	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id
 	LEFT JOIN user U ON T.user_id = U.id
 	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
 	WHERE (? IS NULL OR DATE(T.datetime) = ?) AND (? IS NULL OR T.posting_date = ?)
 	ORDER BY T.datetime, AT.transaction_id, AT.type DESC, AT.amount ASC
 `
 
+const JournalEntriesForAuditPage = `
+	SELECT T.datetime, U.name AS issuer, AT.transaction_id, A.name AS account, AT.type, T.posting_date, AT.amount,  T.remark, AT.id,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
+	FROM transaction T
+	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id
+	LEFT JOIN user U ON T.user_id = U.id
+	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
+	WHERE (? IS NULL OR DATE(T.datetime) = ?) AND (? IS NULL OR T.posting_date = ?)
+		AND (? IS NULL OR (AT.transaction_id, IF(AT.type = 'DR', 0, 1), AT.id) > (?, ?, ?))
+	ORDER BY AT.transaction_id, IF(AT.type = 'DR', 0, 1), AT.id
+	LIMIT ?
+`
+
+const JournalEntriesForAuditCount = `
+	SELECT COUNT(*)
+	FROM transaction T
+	JOIN account_transaction AT ON AT.transaction_id = T.id
+	WHERE (? IS NULL OR DATE(T.datetime) = ?) AND (? IS NULL OR T.posting_date = ?)
+`
+
 const AccountBalancesForReporting = `
 	SELECT A.id, MA.name as main_account, SA.name as sub_account, AC.name as account_category, A.name, COALESCE(AT.debit-AT.credit, 0) AS balance 
 	FROM account A 
@@ -129,3 +256,1065 @@ const AccountSummariesForPnl = `
 	WHERE main_account IN ("Expenses", "Cost of Sales", "Revenue", "Other Revenue")
 	ORDER BY FIELD(main_account, "Expenses", "Cost of Sales", "Revenue", "Other Revenue"), sub_account, account_category, ABS(balance) DESC
 `
+
+const AccountCodes = `
+	SELECT COALESCE(account_id, '') AS account_id, id FROM account
+`
+
+const OpeningBalanceTransactions = `
+	SELECT transaction_id FROM opening_balance FOR UPDATE
+`
+
+const DeleteAccountTransactions = `
+	DELETE FROM account_transaction WHERE transaction_id = ?
+`
+
+const DeleteOpeningBalance = `
+	DELETE FROM opening_balance WHERE transaction_id = ?
+`
+
+const DeleteTransaction = `
+	DELETE FROM transaction WHERE id = ?
+`
+
+const AccountName = `
+	SELECT name FROM account WHERE id = ?
+`
+
+const LedgerOpeningBalance = `
+	SELECT COALESCE(SUM(CASE WHEN AT.type = "DR" THEN AT.amount ELSE -AT.amount END), 0) AS balance
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.posting_date < ?
+`
+
+const LedgerStatement = `
+	SELECT AT.id, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, AT.type, AT.amount, COALESCE(T.remark, '') AS remark,
+		COALESCE(GROUP_CONCAT(DISTINCT CA.name ORDER BY CA.name SEPARATOR ', '), '') AS counterparties,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN account_transaction CAT ON CAT.transaction_id = AT.transaction_id AND CAT.type != AT.type
+	LEFT JOIN account CA ON CA.id = CAT.account_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
+	WHERE AT.account_id = ? AND (? IS NULL OR T.posting_date >= ?) AND (? IS NULL OR T.posting_date <= ?)
+	GROUP BY AT.id, AT.transaction_id, T.posting_date, AT.type, AT.amount, T.remark, RV.reversal_transaction_id, RO.transaction_id
+	ORDER BY T.posting_date, AT.transaction_id, AT.id
+`
+
+const LedgerStatementPage = `
+	SELECT AT.id, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, AT.type, AT.amount, COALESCE(T.remark, '') AS remark,
+		COALESCE(GROUP_CONCAT(DISTINCT CA.name ORDER BY CA.name SEPARATOR ', '), '') AS counterparties,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN account_transaction CAT ON CAT.transaction_id = AT.transaction_id AND CAT.type != AT.type
+	LEFT JOIN account CA ON CA.id = CAT.account_id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
+	WHERE AT.account_id = ? AND (? IS NULL OR T.posting_date >= ?) AND (? IS NULL OR T.posting_date <= ?)
+		AND (? IS NULL OR (T.posting_date, AT.transaction_id, AT.id) > (?, ?, ?))
+	GROUP BY AT.id, AT.transaction_id, T.posting_date, AT.type, AT.amount, T.remark, RV.reversal_transaction_id, RO.transaction_id
+	ORDER BY T.posting_date, AT.transaction_id, AT.id
+	LIMIT ?
+`
+
+const LedgerStatementCount = `
+	SELECT COUNT(*)
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND (? IS NULL OR T.posting_date >= ?) AND (? IS NULL OR T.posting_date <= ?)
+`
+
+const LedgerBalanceThrough = `
+	SELECT COALESCE(SUM(CASE WHEN AT.type = "DR" THEN AT.amount ELSE -AT.amount END), 0) AS balance
+	FROM account_transaction AT
+	LEFT JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND (T.posting_date, AT.transaction_id, AT.id) <= (?, ?, ?)
+`
+
+const auditTrailSelect = `
+	SELECT T.datetime, COALESCE(U.name, '') AS issuer, AT.transaction_id,
+		CASE WHEN PV.id IS NOT NULL THEN 'voucher' WHEN D.id IS NOT NULL THEN 'deposit' WHEN RCV.id IS NOT NULL THEN 'receipt' WHEN VB.id IS NOT NULL THEN 'bill' WHEN OB.transaction_id IS NOT NULL THEN 'opening' WHEN RO.transaction_id IS NOT NULL THEN 'reversal' ELSE 'journal' END AS transaction_type,
+		A.name AS account, AT.type, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, AT.amount, COALESCE(T.remark, '') AS remark, AT.id,
+		DAYOFWEEK(T.posting_date) IN (1, 7) OR DAYOFWEEK(T.datetime) IN (1, 7) AS weekend_posting,
+		AT.amount >= ? AND MOD(AT.amount, ?) = 0 AS round_amount,
+		T.posting_date < DATE(T.datetime) AS back_dated,
+		COALESCE(DF.approved_by = DF.posted_by, 0) AS self_approved,
+		COALESCE(RV.reversal_transaction_id, RO.transaction_id, 0) AS linked_transaction_id
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN user U ON U.id = T.user_id
+	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN payment_voucher PV ON PV.transaction_id = T.id
+	LEFT JOIN deposit D ON D.transaction_id = T.id
+	LEFT JOIN receipt_voucher RCV ON RCV.transaction_id = T.id
+	LEFT JOIN vendor_bill VB ON VB.transaction_id = T.id
+	LEFT JOIN opening_balance OB ON OB.transaction_id = T.id
+	LEFT JOIN draft DF ON DF.transaction_id = T.id
+	LEFT JOIN transaction_reversal RV ON RV.transaction_id = AT.transaction_id
+	LEFT JOIN transaction_reversal RO ON RO.reversal_transaction_id = AT.transaction_id
+	WHERE (? IS NULL OR DATE(T.datetime) >= ?) AND (? IS NULL OR DATE(T.datetime) <= ?)
+		AND (? IS NULL OR T.posting_date >= ?) AND (? IS NULL OR T.posting_date <= ?)
+		AND (? IS NULL OR T.user_id = ?)
+		AND (? IS NULL OR EXISTS (SELECT 1 FROM account_transaction F WHERE F.transaction_id = T.id AND F.account_id = ?))
+		AND ((? IS NULL AND ? IS NULL) OR EXISTS (SELECT 1 FROM account_transaction F WHERE F.transaction_id = T.id AND F.amount >= COALESCE(?, F.amount) AND F.amount <= COALESCE(?, F.amount)))
+		AND (? IS NULL OR T.remark LIKE CONCAT('%', ?, '%'))
+`
+
+const auditTrailHaving = `
+	HAVING (? IS NULL OR transaction_type = ?) AND (? = 0 OR weekend_posting OR round_amount OR back_dated OR self_approved)
+`
+
+const AuditTrail = auditTrailSelect + `
+		AND (? IS NULL OR (AT.transaction_id, IF(AT.type = 'DR', 0, 1), AT.id) > (?, ?, ?))
+` + auditTrailHaving + `
+	ORDER BY AT.transaction_id, IF(AT.type = 'DR', 0, 1), AT.id
+	LIMIT ?
+`
+
+const AuditTrailCount = `
+	SELECT COUNT(*) FROM (` + auditTrailSelect + auditTrailHaving + `) X
+`
+
+const CreateTransactionLogLock = `
+	INSERT IGNORE INTO transaction_log_lock (id) VALUES (1)
+`
+
+const TransactionLogLock = `
+	SELECT id FROM transaction_log_lock WHERE id = 1 FOR UPDATE
+`
+
+const LastTransactionLog = `
+	SELECT hash FROM transaction_log ORDER BY id DESC LIMIT 1 FOR UPDATE
+`
+
+const TransactionForLog = `
+	SELECT COALESCE(user_id, ''), DATE_FORMAT(datetime, '%Y-%m-%d %H:%i:%s'), DATE_FORMAT(posting_date, '%Y-%m-%d'), COALESCE(contract_id, ''), COALESCE(remark, '')
+	FROM transaction WHERE id = ?
+`
+
+const TransactionLinesForLog = `
+	SELECT account_id, type, amount FROM account_transaction WHERE transaction_id = ? ORDER BY id
+`
+
+const TransactionLog = `
+	SELECT id, transaction_id, event, COALESCE(content_hash, '') AS content_hash, COALESCE(previous_hash, '') AS previous_hash, hash FROM transaction_log ORDER BY id
+`
+
+const LastTransactionLogEvents = `
+	SELECT transaction_id, MAX(id) FROM transaction_log GROUP BY transaction_id
+`
+
+const UnloggedTransaction = `
+	SELECT T.id
+	FROM transaction T
+	LEFT JOIN transaction_log L ON L.transaction_id = T.id
+	WHERE L.id IS NULL AND T.id > (SELECT MIN(transaction_id) FROM transaction_log)
+	ORDER BY T.id
+	LIMIT 1
+`
+
+const ChangeHistory = `
+	SELECT C.id, C.table_name, C.record_id, C.field, COALESCE(C.old_value, '') AS old_value, COALESCE(C.new_value, '') AS new_value, COALESCE(U.name, '') AS user, C.datetime
+	FROM change_log C
+	LEFT JOIN user U ON U.id = C.user_id
+	WHERE C.table_name = ? AND C.record_id = ?
+	ORDER BY C.id
+`
+
+const ChangeHistoryPage = `
+	SELECT C.id, C.table_name, C.record_id, C.field, COALESCE(C.old_value, '') AS old_value, COALESCE(C.new_value, '') AS new_value, COALESCE(U.name, '') AS user, C.datetime
+	FROM change_log C
+	LEFT JOIN user U ON U.id = C.user_id
+	WHERE C.table_name = ? AND C.record_id = ? AND (? IS NULL OR C.id > ?)
+	ORDER BY C.id
+	LIMIT ?
+`
+
+const ChangeHistoryCount = `
+	SELECT COUNT(*) FROM change_log WHERE table_name = ? AND record_id = ?
+`
+
+const draftColumns = `
+	SELECT D.id, D.type, D.status, COALESCE(D.user_id, '') AS user_id, COALESCE(DATE_FORMAT(D.posting_date, '%Y-%m-%d'), '') AS posting_date, COALESCE(D.remark, '') AS remark,
+		COALESCE(D.entries, '') AS entries, COALESCE(D.from_account_id, '') AS from_account_id, COALESCE(D.amount, '') AS amount, COALESCE(DATE_FORMAT(D.due_date, '%Y-%m-%d'), '') AS due_date,
+		COALESCE(D.check_number, '') AS check_number, COALESCE(D.payee, '') AS payee, COALESCE(D.approved_by, '') AS approved_by, COALESCE(D.posted_by, '') AS posted_by, COALESCE(D.transaction_id, 0) AS transaction_id, D.datetime,
+		COALESCE(D.attempts, 0) AS attempts, COALESCE(D.last_error, '') AS last_error, COALESCE(DATE_FORMAT(D.reverse_on, '%Y-%m-%d'), '') AS reverse_on
+	FROM draft D
+`
+
+const Drafts = draftColumns + `
+	WHERE (? IS NULL OR D.status = ?)
+	ORDER BY D.id
+`
+
+const DraftsPage = draftColumns + `
+	WHERE (? IS NULL OR D.status = ?) AND (? IS NULL OR D.id > ?)
+	ORDER BY D.id
+	LIMIT ?
+`
+
+const DraftsCount = `
+	SELECT COUNT(*) FROM draft D WHERE (? IS NULL OR D.status = ?)
+`
+
+const Draft = draftColumns + `
+	WHERE D.id = ?
+`
+
+const DraftForUpdate = draftColumns + `
+	WHERE D.id = ?
+	FOR UPDATE
+`
+
+const ApprovalLimits = `
+	SELECT COALESCE(account_id, 0) AS account_id, max_amount FROM approval_limit WHERE user_id = ?
+`
+
+const DueDrafts = `
+	SELECT id FROM draft WHERE status = 'scheduled' AND posting_date <= ? ORDER BY posting_date, id
+`
+
+const DraftFailed = `
+	UPDATE draft SET attempts = COALESCE(attempts, 0) + 1, last_error = ? WHERE id = ?
+`
+
+const RecurringTemplates = `
+	SELECT id, name, COALESCE(remark, '') AS remark, schedule, DATE_FORMAT(start_date, '%Y-%m-%d') AS start_date, COALESCE(DATE_FORMAT(end_date, '%Y-%m-%d'), '') AS end_date,
+		COALESCE(max_occurrences, 0) AS max_occurrences, user_id, active
+	FROM recurring_template
+	WHERE (? IS NULL OR active = ?)
+	ORDER BY id
+`
+
+const RecurringTemplatesPage = `
+	SELECT id, name, COALESCE(remark, '') AS remark, schedule, DATE_FORMAT(start_date, '%Y-%m-%d') AS start_date, COALESCE(DATE_FORMAT(end_date, '%Y-%m-%d'), '') AS end_date,
+		COALESCE(max_occurrences, 0) AS max_occurrences, user_id, active
+	FROM recurring_template
+	WHERE (? IS NULL OR id > ?)
+	ORDER BY id
+	LIMIT ?
+`
+
+const RecurringTemplatesCount = `
+	SELECT COUNT(*) FROM recurring_template
+`
+
+const RecurringTemplate = `
+	SELECT id, name, COALESCE(remark, '') AS remark, schedule, DATE_FORMAT(start_date, '%Y-%m-%d') AS start_date, COALESCE(DATE_FORMAT(end_date, '%Y-%m-%d'), '') AS end_date,
+		COALESCE(max_occurrences, 0) AS max_occurrences, user_id, active
+	FROM recurring_template
+	WHERE id = ?
+`
+
+const RecurringTemplateForUpdate = `
+	SELECT id FROM recurring_template WHERE id = ? FOR UPDATE
+`
+
+const RecurringOccurrenceFailed = `
+	INSERT IGNORE INTO recurring_occurrence (template_id, occurrence_date, error) VALUES (?, ?, ?)
+`
+
+const FailedRecurringOccurrences = `
+	SELECT template_id, DATE_FORMAT(occurrence_date, '%Y-%m-%d') AS date, 0 AS transaction_id, 0 AS draft_id, error
+	FROM recurring_occurrence
+	WHERE error IS NOT NULL
+	ORDER BY template_id, occurrence_date
+`
+
+const FailedRecurringOccurrencesPage = `
+	SELECT template_id, DATE_FORMAT(occurrence_date, '%Y-%m-%d') AS date, 0 AS transaction_id, 0 AS draft_id, error
+	FROM recurring_occurrence
+	WHERE error IS NOT NULL AND (? IS NULL OR (template_id, occurrence_date) > (?, ?))
+	ORDER BY template_id, occurrence_date
+	LIMIT ?
+`
+
+const FailedRecurringOccurrencesCount = `
+	SELECT COUNT(*) FROM recurring_occurrence WHERE error IS NOT NULL
+`
+
+const DeleteFailedRecurringOccurrence = `
+	DELETE FROM recurring_occurrence WHERE template_id = ? AND occurrence_date = ? AND error IS NOT NULL
+`
+
+const RecurringOccurrenceDates = `
+	SELECT DATE_FORMAT(occurrence_date, '%Y-%m-%d') FROM recurring_occurrence WHERE template_id = ?
+`
+
+const RecurringLines = `
+	SELECT account_id, type, amount
+	FROM recurring_template_line
+	WHERE template_id = ? AND effective_from = (
+		SELECT MAX(effective_from) FROM recurring_template_line WHERE template_id = ? AND effective_from <= ?
+	)
+	ORDER BY id
+`
+
+const TransactionContract = `
+	SELECT COALESCE(contract_id, '') FROM transaction WHERE id = ?
+`
+
+const TransactionReversal = `
+	SELECT reversal_transaction_id FROM transaction_reversal WHERE transaction_id = ?
+`
+
+const ChequeBookCount = `
+	SELECT COUNT(*) FROM cheque_book WHERE account_id = ?
+`
+
+const ChequeBookOverlaps = `
+	SELECT COUNT(*) FROM cheque_book WHERE account_id = ? AND first_number <= ? AND last_number >= ?
+`
+
+const ChequeBookForNumber = `
+	SELECT id FROM cheque_book WHERE account_id = ? AND active = 1 AND ? BETWEEN first_number AND last_number
+`
+
+const ChequeBooks = `
+	SELECT CB.id, CB.account_id, A.name AS account_name, CB.first_number, CB.last_number, COUNT(C.id) AS used,
+		COALESCE(MAX(C.number) + 1, CB.first_number) AS next_number, CB.active
+	FROM cheque_book CB
+	LEFT JOIN account A ON A.id = CB.account_id
+	LEFT JOIN cheque C ON C.cheque_book_id = CB.id
+	WHERE (? IS NULL OR CB.account_id = ?)
+	GROUP BY CB.id, CB.account_id, A.name, CB.first_number, CB.last_number, CB.active
+	ORDER BY CB.account_id, CB.first_number
+`
+
+const ChequeBookRange = `
+	SELECT first_number, last_number FROM cheque_book WHERE id = ?
+`
+
+const ChequeBookNumbers = `
+	SELECT number FROM cheque WHERE cheque_book_id = ? ORDER BY number
+`
+
+const ChequeUsed = `
+	SELECT COUNT(*) FROM cheque WHERE account_id = ? AND number = ?
+`
+
+const ChequeForVoucher = `
+	SELECT COUNT(*) FROM cheque WHERE payment_voucher_id = ?
+`
+
+const chequeColumns = `
+	SELECT C.id, C.cheque_book_id, C.account_id, COALESCE(A.name, '') AS account_name, C.number, COALESCE(C.payment_voucher_id, 0) AS payment_voucher_id,
+		COALESCE(C.transaction_id, 0) AS transaction_id, COALESCE(DATE_FORMAT(T.posting_date, '%Y-%m-%d'), '') AS posting_date,
+		COALESCE(DATE_FORMAT(PV.due_date, '%Y-%m-%d'), '') AS due_date, COALESCE(PV.payee, '') AS payee, COALESCE(C.amount, 0) AS amount, C.status,
+		COALESCE(DATE_FORMAT(C.status_date, '%Y-%m-%d'), '') AS status_date, COALESCE(C.reversal_transaction_id, 0) AS reversal_transaction_id
+	FROM cheque C
+	LEFT JOIN account A ON A.id = C.account_id
+	LEFT JOIN payment_voucher PV ON PV.id = C.payment_voucher_id
+	LEFT JOIN transaction T ON T.id = C.transaction_id
+`
+
+const Cheques = chequeColumns + `
+	WHERE (? IS NULL OR C.account_id = ?) AND (? IS NULL OR C.status = ?)
+	ORDER BY C.account_id, C.number
+`
+
+const ChequesPage = chequeColumns + `
+	WHERE (? IS NULL OR C.account_id = ?) AND (? IS NULL OR C.status = ?)
+		AND (? IS NULL OR (C.account_id, C.number, C.id) > (?, ?, ?))
+	ORDER BY C.account_id, C.number, C.id
+	LIMIT ?
+`
+
+const ChequesCount = `
+	SELECT COUNT(*) FROM cheque C WHERE (? IS NULL OR C.account_id = ?) AND (? IS NULL OR C.status = ?)
+`
+
+const ChequeForUpdate = chequeColumns + `
+	WHERE C.id = ?
+	FOR UPDATE
+`
+
+const PostDatedCheques = chequeColumns + `
+	WHERE C.status = 'issued' AND PV.due_date > ?
+	ORDER BY PV.due_date, C.account_id, C.number
+`
+
+const PostDatedChequesPage = chequeColumns + `
+	WHERE C.status = 'issued' AND PV.due_date > ?
+		AND (? IS NULL OR (PV.due_date, C.account_id, C.number, C.id) > (?, ?, ?, ?))
+	ORDER BY PV.due_date, C.account_id, C.number, C.id
+	LIMIT ?
+`
+
+const PostDatedChequesCount = `
+	SELECT COUNT(*)
+	FROM cheque C
+	JOIN payment_voucher PV ON PV.id = C.payment_voucher_id
+	WHERE C.status = 'issued' AND PV.due_date > ?
+`
+
+const StaleCheques = `
+	SELECT C.id
+	FROM cheque C
+	LEFT JOIN payment_voucher PV ON PV.id = C.payment_voucher_id
+	LEFT JOIN transaction T ON T.id = C.transaction_id
+	WHERE C.status = 'issued' AND COALESCE(PV.due_date, T.posting_date) <= ?
+	ORDER BY C.id
+`
+
+const TransactionRemark = `
+	SELECT COALESCE(remark, '') FROM transaction WHERE id = ?
+`
+
+const PrintCount = `
+	SELECT COUNT(*) FROM print_log WHERE document = ? AND record_id = ? FOR UPDATE
+`
+
+const PrintHistory = `
+	SELECT id, document, record_id, copy, COALESCE(reason, '') AS reason, user_id, DATE_FORMAT(datetime, '%Y-%m-%d %H:%i:%s') AS datetime
+	FROM print_log
+	WHERE document = ? AND record_id = ?
+	ORDER BY id
+`
+
+const PrintHistoryPage = `
+	SELECT id, document, record_id, copy, COALESCE(reason, '') AS reason, user_id, DATE_FORMAT(datetime, '%Y-%m-%d %H:%i:%s') AS datetime
+	FROM print_log
+	WHERE document = ? AND record_id = ? AND (? IS NULL OR id > ?)
+	ORDER BY id
+	LIMIT ?
+`
+
+const PrintHistoryCount = `
+	SELECT COUNT(*) FROM print_log WHERE document = ? AND record_id = ?
+`
+
+const ChequeStatusForVoucher = `
+	SELECT number, status FROM cheque WHERE payment_voucher_id = ?
+`
+
+const ReceiptBooks = `
+	SELECT id, name, COALESCE(prefix, '') AS prefix, next_number, active FROM receipt_book ORDER BY id
+`
+
+const ReceiptBookForUpdate = `
+	SELECT COALESCE(prefix, ''), next_number, active FROM receipt_book WHERE id = ? FOR UPDATE
+`
+
+const receiptVoucherList = `
+	SELECT RV.id, CONCAT(COALESCE(RB.prefix, ''), RV.receipt_number) AS receipt_number, T.datetime, T.posting_date, A.name AS to_account,
+		COALESCE(RV.payer, '') AS payer, RV.payment_method, U.name AS user
+	FROM receipt_voucher RV
+	LEFT JOIN receipt_book RB ON RB.id = RV.receipt_book_id
+	LEFT JOIN transaction T ON T.id = RV.transaction_id
+	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'DR'
+	LEFT JOIN account A ON A.id = AT.account_id
+	LEFT JOIN user U ON T.user_id = U.id
+`
+
+const ReceiptVouchers = receiptVoucherList + `
+	ORDER BY T.datetime DESC
+`
+
+const ReceiptVouchersPage = receiptVoucherList + `
+	WHERE (? IS NULL OR RV.id < ?)
+	ORDER BY RV.id DESC
+	LIMIT ?
+`
+
+const ReceiptVouchersCount = `
+	SELECT COUNT(*) FROM receipt_voucher
+`
+
+const ReceiptVoucher = `
+	SELECT CONCAT(COALESCE(RB.prefix, ''), RV.receipt_number) AS receipt_number, COALESCE(RV.payer, '') AS payer, RV.payment_method,
+		COALESCE(RV.cheque_number, '') AS cheque_number, COALESCE(RV.cheque_bank, '') AS cheque_bank, COALESCE(DATE_FORMAT(RV.cheque_date, '%Y-%m-%d'), '') AS cheque_date,
+		COALESCE(T.remark, '') AS remark, COALESCE(A.name, '') AS account_name, DATE_FORMAT(T.datetime, '%Y-%m-%d %H:%i:%s') AS datetime
+	FROM receipt_voucher RV
+	LEFT JOIN receipt_book RB ON RB.id = RV.receipt_book_id
+	LEFT JOIN transaction T ON T.id = RV.transaction_id
+	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'DR'
+	LEFT JOIN account A ON A.id = AT.account_id
+	WHERE RV.id = ?
+`
+
+const ReceiptVoucherDetails = `
+	SELECT A.account_id, A.name AS account_name, AT.amount, DATE(T.posting_date) as posting_date
+	FROM receipt_voucher RV
+	LEFT JOIN transaction T ON T.id = RV.transaction_id
+	LEFT JOIN account_transaction AT ON AT.transaction_id = T.id AND AT.type = 'CR'
+	LEFT JOIN account A ON A.id = AT.account_id
+	WHERE RV.id = ?
+`
+
+const CreateNumberSeries = `
+	INSERT IGNORE INTO number_series (document_type, branch, fiscal_year, next_number) VALUES (?, ?, ?, 1)
+`
+
+const NumberSeriesForUpdate = `
+	SELECT id, next_number FROM number_series WHERE document_type = ? AND branch = ? AND fiscal_year = ? FOR UPDATE
+`
+
+const MoveDocumentNumber = `
+	UPDATE document_number DN
+	JOIN number_series NS ON NS.id = DN.number_series_id
+	SET DN.transaction_id = ?
+	WHERE DN.transaction_id = ? AND NS.document_type = ? AND NS.branch = ? AND NS.fiscal_year = ?
+`
+
+const DeleteDocumentNumber = `
+	DELETE FROM document_number WHERE transaction_id = ?
+`
+
+const DocumentNumber = `
+	SELECT document_number FROM document_number WHERE transaction_id = ?
+`
+
+const TransactionByDocumentNumber = `
+	SELECT transaction_id FROM document_number WHERE document_number = ?
+`
+
+const BankStatementOverlaps = `
+	SELECT COUNT(*) FROM bank_statement WHERE account_id = ? AND start_date <= ? AND end_date >= ?
+`
+
+const BankStatement = `
+	SELECT account_id, DATE_FORMAT(start_date, '%Y-%m-%d'), DATE_FORMAT(end_date, '%Y-%m-%d'), closing_balance, status
+	FROM bank_statement WHERE id = ?
+`
+
+const BankStatementForUpdate = BankStatement + `
+	FOR UPDATE
+`
+
+const BankStatements = `
+	SELECT BS.id, BS.account_id, DATE_FORMAT(BS.start_date, '%Y-%m-%d') AS start_date, DATE_FORMAT(BS.end_date, '%Y-%m-%d') AS end_date,
+		BS.opening_balance, BS.closing_balance, BS.status, COUNT(BL.id) AS lines, COUNT(BML.bank_statement_line_id) AS matched
+	FROM bank_statement BS
+	LEFT JOIN bank_statement_line BL ON BL.bank_statement_id = BS.id
+	LEFT JOIN bank_match_line BML ON BML.bank_statement_line_id = BL.id
+	WHERE (? IS NULL OR BS.account_id = ?)
+	GROUP BY BS.id, BS.account_id, BS.start_date, BS.end_date, BS.opening_balance, BS.closing_balance, BS.status
+	ORDER BY BS.account_id, BS.start_date
+`
+
+const BankStatementsPage = `
+	SELECT BS.id, BS.account_id, DATE_FORMAT(BS.start_date, '%Y-%m-%d') AS start_date, DATE_FORMAT(BS.end_date, '%Y-%m-%d') AS end_date,
+		BS.opening_balance, BS.closing_balance, BS.status, COUNT(BL.id) AS lines, COUNT(BML.bank_statement_line_id) AS matched
+	FROM bank_statement BS
+	LEFT JOIN bank_statement_line BL ON BL.bank_statement_id = BS.id
+	LEFT JOIN bank_match_line BML ON BML.bank_statement_line_id = BL.id
+	WHERE (? IS NULL OR BS.account_id = ?) AND (? IS NULL OR (BS.account_id, BS.start_date, BS.id) > (?, ?, ?))
+	GROUP BY BS.id, BS.account_id, BS.start_date, BS.end_date, BS.opening_balance, BS.closing_balance, BS.status
+	ORDER BY BS.account_id, BS.start_date, BS.id
+	LIMIT ?
+`
+
+const BankStatementsCount = `
+	SELECT COUNT(*) FROM bank_statement WHERE (? IS NULL OR account_id = ?)
+`
+
+const bankStatementLines = `
+	SELECT BL.id, DATE_FORMAT(BL.line_date, '%Y-%m-%d') AS date, COALESCE(BL.description, '') AS description, COALESCE(BL.counterparty, '') AS counterparty,
+		COALESCE(BL.reference, '') AS reference,
+		COALESCE(BL.cheque_number, '') AS cheque_number, BL.amount, COALESCE(BML.bank_match_id, 0) AS match_id
+	FROM bank_statement_line BL
+	LEFT JOIN bank_match_line BML ON BML.bank_statement_line_id = BL.id
+	WHERE BL.bank_statement_id = ?
+`
+
+const BankStatementLines = bankStatementLines + `
+	ORDER BY BL.line_date, BL.id
+`
+
+const BankStatementLinesPage = bankStatementLines + `
+		AND (? IS NULL OR (BL.line_date, BL.id) > (?, ?))
+	ORDER BY BL.line_date, BL.id
+	LIMIT ?
+`
+
+const BankStatementLinesCount = `
+	SELECT COUNT(*) FROM bank_statement_line WHERE bank_statement_id = ?
+`
+
+const UnmatchedStatementLines = bankStatementLines + `
+		AND BML.bank_match_id IS NULL
+	ORDER BY BL.line_date, BL.id
+`
+
+const UncategorizedStatementLines = bankStatementLines + `
+		AND BML.bank_match_id IS NULL
+		AND NOT EXISTS (SELECT 1 FROM bank_line_proposal BLP WHERE BLP.bank_statement_line_id = BL.id)
+	ORDER BY BL.line_date, BL.id
+`
+
+const StatementLineForUpdate = bankStatementLines + `
+		AND BL.id = ?
+	FOR UPDATE
+`
+
+const StatementLineStatement = `
+	SELECT bank_statement_id FROM bank_statement_line WHERE id = ?
+`
+
+const bookEntries = `
+	SELECT AT.id, AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date,
+		CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END AS amount,
+		COALESCE(PV.check_number, RCV.cheque_number, '') AS cheque_number, COALESCE(T.remark, '') AS remark
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	LEFT JOIN payment_voucher PV ON PV.transaction_id = T.id
+	LEFT JOIN receipt_voucher RCV ON RCV.transaction_id = T.id
+`
+
+const unmatchedBookEntries = `
+	LEFT JOIN bank_match_entry BME ON BME.account_transaction_id = AT.id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND BME.bank_match_id IS NULL
+`
+
+const UnmatchedBookEntries = bookEntries + unmatchedBookEntries + `
+	ORDER BY T.posting_date, AT.id
+`
+
+const UnmatchedBookEntriesForUpdate = UnmatchedBookEntries + `
+	FOR UPDATE
+`
+
+const UnmatchedBookEntriesPage = bookEntries + unmatchedBookEntries + `
+		AND (? IS NULL OR (T.posting_date, AT.id) > (?, ?))
+	ORDER BY T.posting_date, AT.id
+	LIMIT ?
+`
+
+const UnmatchedBookEntriesCount = `
+	SELECT COUNT(*)
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+` + unmatchedBookEntries
+
+const OutstandingBookEntries = bookEntries + `
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND T.posting_date >= ? AND NOT EXISTS (
+		SELECT 1
+		FROM bank_match_entry BME
+		JOIN bank_match_line BML ON BML.bank_match_id = BME.bank_match_id
+		JOIN bank_statement_line BL ON BL.id = BML.bank_statement_line_id
+		WHERE BME.account_transaction_id = AT.id AND BL.line_date <= ?
+	)
+	ORDER BY T.posting_date, AT.id
+`
+
+const FirstBankStatement = `
+	SELECT DATE_FORMAT(start_date, '%Y-%m-%d'), opening_balance FROM bank_statement WHERE account_id = ? ORDER BY start_date LIMIT 1
+`
+
+const MatchedBookEntriesBefore = `
+	SELECT COALESCE(SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END), 0)
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.posting_date < ? AND EXISTS (
+		SELECT 1
+		FROM bank_match_entry BME
+		JOIN bank_match_line BML ON BML.bank_match_id = BME.bank_match_id
+		JOIN bank_statement_line BL ON BL.id = BML.bank_statement_line_id
+		WHERE BME.account_transaction_id = AT.id AND BL.line_date <= ?
+	)
+`
+
+const BankMatchStatement = `
+	SELECT DISTINCT BL.bank_statement_id
+	FROM bank_match_line BML
+	JOIN bank_statement_line BL ON BL.id = BML.bank_statement_line_id
+	WHERE BML.bank_match_id = ?
+`
+
+const DeleteBankMatchLines = `
+	DELETE FROM bank_match_line WHERE bank_match_id = ?
+`
+
+const DeleteBankMatchEntries = `
+	DELETE FROM bank_match_entry WHERE bank_match_id = ?
+`
+
+const DeleteBankMatch = `
+	DELETE FROM bank_match WHERE id = ?
+`
+
+const BankRules = `
+	SELECT id, name, priority, COALESCE(bank_account_id, 0) AS bank_account_id, COALESCE(pattern, '') AS pattern,
+		COALESCE(counterparty, '') AS counterparty, COALESCE(direction, '') AS direction, COALESCE(min_amount, 0) AS min_amount,
+		COALESCE(max_amount, 0) AS max_amount, account_id, auto_post, active
+	FROM bank_rule
+	WHERE (? IS NULL OR active = ?)
+	ORDER BY priority, id
+`
+
+const BankLineProposal = `
+	SELECT bank_rule_id, account_id FROM bank_line_proposal WHERE bank_statement_line_id = ? AND status = 'proposed'
+`
+
+const RejectBankLineProposal = `
+	UPDATE bank_line_proposal SET status = 'rejected' WHERE bank_statement_line_id = ? AND status = 'proposed'
+`
+
+const DeleteBankLineProposal = `
+	DELETE FROM bank_line_proposal WHERE bank_statement_line_id = ?
+`
+
+const BankLineDraftCount = `
+	SELECT COUNT(*) FROM bank_line_proposal WHERE bank_statement_line_id = ? AND status = 'drafted'
+`
+
+const DraftBankLine = `
+	SELECT BL.id, BL.bank_statement_id
+	FROM bank_line_proposal BLP
+	JOIN bank_statement_line BL ON BL.id = BLP.bank_statement_line_id
+	WHERE BLP.draft_id = ? AND BLP.status = 'drafted'
+`
+
+const DeleteDraftBankLineProposal = `
+	DELETE FROM bank_line_proposal WHERE draft_id = ? AND status = 'drafted'
+`
+
+const TransactionBookEntries = bookEntries + `
+	WHERE AT.transaction_id = ? AND AT.account_id = ?
+`
+
+const TransactionAccountEntry = `
+	SELECT id FROM account_transaction WHERE transaction_id = ? AND account_id = ? ORDER BY id LIMIT 1
+`
+
+const bankReviewQueue = `
+	SELECT BL.id AS line_id, DATE_FORMAT(BL.line_date, '%Y-%m-%d') AS date, COALESCE(BL.description, '') AS description,
+		COALESCE(BL.counterparty, '') AS counterparty, COALESCE(BL.reference, '') AS reference, BL.amount,
+		COALESCE(BR.id, 0) AS rule_id, COALESCE(BR.name, '') AS rule_name, COALESCE(A.id, 0) AS account_id, COALESCE(A.name, '') AS account_name,
+		COALESCE(BLD.draft_id, 0) AS draft_id
+	FROM bank_statement_line BL
+	LEFT JOIN bank_match_line BML ON BML.bank_statement_line_id = BL.id
+	LEFT JOIN bank_line_proposal BLP ON BLP.bank_statement_line_id = BL.id AND BLP.status = 'proposed'
+	LEFT JOIN bank_line_proposal BLD ON BLD.bank_statement_line_id = BL.id AND BLD.status = 'drafted'
+	LEFT JOIN bank_rule BR ON BR.id = BLP.bank_rule_id
+	LEFT JOIN account A ON A.id = BLP.account_id
+	WHERE BL.bank_statement_id = ? AND BML.bank_match_id IS NULL
+`
+
+const BankReviewQueue = bankReviewQueue + `
+	ORDER BY BL.line_date, BL.id
+`
+
+const BankReviewQueuePage = bankReviewQueue + `
+		AND (? IS NULL OR (BL.line_date, BL.id) > (?, ?))
+	ORDER BY BL.line_date, BL.id
+	LIMIT ?
+`
+
+const BankReviewQueueCount = `
+	SELECT COUNT(*)
+	FROM bank_statement_line BL
+	LEFT JOIN bank_match_line BML ON BML.bank_statement_line_id = BL.id
+	WHERE BL.bank_statement_id = ? AND BML.bank_match_id IS NULL
+`
+
+const ContractBalances = `
+	SELECT T.contract_id, SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END) AS debit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END) AS credit,
+		SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END) AS balance
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND COALESCE(T.contract_id, '') <> '' AND (? IS NULL OR T.contract_id = ?)
+	GROUP BY T.contract_id
+	ORDER BY T.contract_id
+`
+
+const ContractBalancesPage = `
+	SELECT T.contract_id, SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END) AS debit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END) AS credit,
+		SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE -AT.amount END) AS balance
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND COALESCE(T.contract_id, '') <> '' AND (? IS NULL OR T.contract_id > ?)
+	GROUP BY T.contract_id
+	ORDER BY T.contract_id
+	LIMIT ?
+`
+
+const ContractBalancesCount = `
+	SELECT COUNT(DISTINCT T.contract_id)
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND COALESCE(T.contract_id, '') <> ''
+`
+
+const receivableItems = `
+	SELECT X.*, X.amount - X.allocated AS outstanding FROM (
+		SELECT AT.id AS entry_id, AT.transaction_id, T.contract_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date,
+			COALESCE(T.remark, '') AS remark, AT.type, AT.amount,
+			CASE WHEN AT.type = 'DR' THEN COALESCE((
+				SELECT SUM(RA.amount)
+				FROM receivable_allocation RA
+				JOIN account_transaction CAT ON CAT.id = RA.credit_entry_id
+				JOIN transaction CT ON CT.id = CAT.transaction_id
+				WHERE RA.charge_entry_id = AT.id AND CT.posting_date <= ?
+			), 0) ELSE COALESCE((
+				SELECT SUM(RA.amount)
+				FROM receivable_allocation RA
+				JOIN account_transaction DAT ON DAT.id = RA.charge_entry_id
+				JOIN transaction DT ON DT.id = DAT.transaction_id
+				WHERE RA.credit_entry_id = AT.id AND DT.posting_date <= ?
+			), 0) END AS allocated
+		FROM account_transaction AT
+		JOIN transaction T ON T.id = AT.transaction_id
+		WHERE AT.account_id = ? AND T.posting_date <= ? AND COALESCE(T.contract_id, '') <> '' AND (? IS NULL OR T.contract_id = ?)
+	) X
+`
+
+const ReceivableItems = receivableItems + `
+	ORDER BY X.contract_id, X.posting_date, X.entry_id
+`
+
+const OpenReceivableItems = receivableItems + `
+	WHERE X.amount <> X.allocated
+	ORDER BY X.contract_id, X.posting_date, X.entry_id
+`
+
+const ReceivableItemsPage = receivableItems + `
+	WHERE (? IS NULL OR (X.contract_id, X.posting_date, X.entry_id) > (?, ?, ?))
+	ORDER BY X.contract_id, X.posting_date, X.entry_id
+	LIMIT ?
+`
+
+const ReceivableItemsCount = `SELECT COUNT(*) FROM (` + ReceivableItems + `) C`
+
+const OpenReceivableItemsPage = receivableItems + `
+	WHERE X.amount <> X.allocated AND (? IS NULL OR (X.contract_id, X.posting_date, X.entry_id) > (?, ?, ?))
+	ORDER BY X.contract_id, X.posting_date, X.entry_id
+	LIMIT ?
+`
+
+const OpenReceivableItemsCount = `SELECT COUNT(*) FROM (` + OpenReceivableItems + `) C`
+
+const ReceivableEntryContract = `
+	SELECT COALESCE(T.contract_id, '')
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.id = ? AND AT.account_id = ?
+`
+
+const LockContractReceivables = `
+	SELECT AT.id
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE AT.account_id = ? AND T.contract_id = ?
+	FOR UPDATE
+`
+
+const ReceivableAllocations = `
+	SELECT RA.id, RA.charge_entry_id, RA.credit_entry_id, RA.amount
+	FROM receivable_allocation RA
+	JOIN account_transaction AT ON AT.id = RA.charge_entry_id
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE T.contract_id = ?
+	ORDER BY RA.id
+`
+
+const ReceivableAllocationsPage = `
+	SELECT RA.id, RA.charge_entry_id, RA.credit_entry_id, RA.amount
+	FROM receivable_allocation RA
+	JOIN account_transaction AT ON AT.id = RA.charge_entry_id
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE T.contract_id = ? AND (? IS NULL OR RA.id > ?)
+	ORDER BY RA.id
+	LIMIT ?
+`
+
+const ReceivableAllocationsCount = `
+	SELECT COUNT(*)
+	FROM receivable_allocation RA
+	JOIN account_transaction AT ON AT.id = RA.charge_entry_id
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE T.contract_id = ?
+`
+
+const ReceivableAllocationContract = `
+	SELECT T.contract_id
+	FROM receivable_allocation RA
+	JOIN account_transaction AT ON AT.id = RA.charge_entry_id
+	JOIN transaction T ON T.id = AT.transaction_id
+	WHERE RA.id = ?
+`
+
+const DeleteReceivableAllocation = `
+	DELETE FROM receivable_allocation WHERE id = ?
+`
+
+const UnassignedControlEntries = bookEntries + `
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND COALESCE(T.contract_id, '') = ''
+	ORDER BY T.posting_date, AT.id
+`
+
+const Vendors = `
+	SELECT id, name, COALESCE(tax_id, '') AS tax_id, COALESCE(bank_name, '') AS bank_name, COALESCE(bank_branch, '') AS bank_branch,
+		COALESCE(bank_account_number, '') AS bank_account_number, payment_terms, active
+	FROM vendor
+	WHERE (? IS NULL OR id = ?)
+	ORDER BY name, id
+`
+
+const VendorsPage = `
+	SELECT id, name, COALESCE(tax_id, '') AS tax_id, COALESCE(bank_name, '') AS bank_name, COALESCE(bank_branch, '') AS bank_branch,
+		COALESCE(bank_account_number, '') AS bank_account_number, payment_terms, active
+	FROM vendor
+	WHERE (? IS NULL OR (name, id) > (?, ?))
+	ORDER BY name, id
+	LIMIT ?
+`
+
+const VendorsCount = `
+	SELECT COUNT(*) FROM vendor
+`
+
+const CopyVendorTransaction = `
+	INSERT INTO vendor_transaction (transaction_id, vendor_id)
+	SELECT ?, vendor_id FROM vendor_transaction WHERE transaction_id = ?
+`
+
+const VendorBillEntry = `
+	SELECT AT.id
+	FROM vendor_bill VB
+	JOIN account_transaction AT ON AT.transaction_id = VB.transaction_id AND AT.account_id = ? AND AT.type = 'CR'
+	WHERE VB.id = ? AND VB.vendor_id = ?
+`
+
+const PayableEntryVendor = `
+	SELECT VT.vendor_id
+	FROM account_transaction AT
+	JOIN vendor_transaction VT ON VT.transaction_id = AT.transaction_id
+	WHERE AT.id = ? AND AT.account_id = ?
+`
+
+const LockVendorPayables = `
+	SELECT AT.id
+	FROM account_transaction AT
+	JOIN vendor_transaction VT ON VT.transaction_id = AT.transaction_id
+	WHERE AT.account_id = ? AND VT.vendor_id = ?
+	FOR UPDATE
+`
+
+const openPayableItems = `
+	SELECT X.*, X.amount - X.allocated AS outstanding FROM (
+		SELECT AT.id AS entry_id, AT.transaction_id, V.id AS vendor_id, V.name AS vendor_name, COALESCE(VB.id, 0) AS bill_id,
+			COALESCE(VB.bill_number, '') AS bill_number, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date,
+			DATE_FORMAT(COALESCE(VB.due_date, T.posting_date), '%Y-%m-%d') AS due_date, COALESCE(T.remark, '') AS remark, AT.type, AT.amount,
+			CASE WHEN AT.type = 'CR' THEN COALESCE((
+				SELECT SUM(PA.amount)
+				FROM payable_allocation PA
+				JOIN account_transaction DAT ON DAT.id = PA.debit_entry_id
+				JOIN transaction DT ON DT.id = DAT.transaction_id
+				WHERE PA.bill_entry_id = AT.id AND DT.posting_date <= ?
+			), 0) ELSE COALESCE((
+				SELECT SUM(PA.amount)
+				FROM payable_allocation PA
+				JOIN account_transaction BAT ON BAT.id = PA.bill_entry_id
+				JOIN transaction BT ON BT.id = BAT.transaction_id
+				WHERE PA.debit_entry_id = AT.id AND BT.posting_date <= ?
+			), 0) END AS allocated
+		FROM account_transaction AT
+		JOIN transaction T ON T.id = AT.transaction_id
+		JOIN vendor_transaction VT ON VT.transaction_id = T.id
+		JOIN vendor V ON V.id = VT.vendor_id
+		LEFT JOIN vendor_bill VB ON VB.transaction_id = T.id
+		WHERE AT.account_id = ? AND T.posting_date <= ? AND (? IS NULL OR V.id = ?)
+	) X
+	WHERE X.amount <> X.allocated
+`
+
+const OpenPayableItems = openPayableItems + `
+	ORDER BY X.vendor_id, X.due_date, X.entry_id
+`
+
+const OpenPayableItemsPage = openPayableItems + `
+		AND (? IS NULL OR (X.vendor_id, X.due_date, X.entry_id) > (?, ?, ?))
+	ORDER BY X.vendor_id, X.due_date, X.entry_id
+	LIMIT ?
+`
+
+const OpenPayableItemsCount = `SELECT COUNT(*) FROM (` + openPayableItems + `) C`
+
+const vendorEntries = `
+	FROM account_transaction AT
+	JOIN transaction T ON T.id = AT.transaction_id
+	JOIN vendor_transaction VT ON VT.transaction_id = T.id
+	JOIN vendor V ON V.id = VT.vendor_id
+`
+
+const VendorBalances = `
+	SELECT V.id AS vendor_id, V.name, SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END) AS debit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END) AS credit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE -AT.amount END) AS balance
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND T.posting_date <= ?
+	GROUP BY V.id, V.name
+	ORDER BY V.name, V.id
+`
+
+const VendorBalancesPage = `
+	SELECT V.id AS vendor_id, V.name, SUM(CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END) AS debit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END) AS credit,
+		SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE -AT.amount END) AS balance
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND (? IS NULL OR (V.name, V.id) > (?, ?))
+	GROUP BY V.id, V.name
+	ORDER BY V.name, V.id
+	LIMIT ?
+`
+
+const VendorBalancesCount = `
+	SELECT COUNT(DISTINCT V.id)
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND T.posting_date <= ?
+`
+
+const VendorOpeningBalance = `
+	SELECT COALESCE(SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE -AT.amount END), 0)
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND V.id = ? AND T.posting_date < ?
+`
+
+const VendorStatementLines = `
+	SELECT AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, COALESCE(VB.bill_number, PV.check_number, '') AS bill_number,
+		COALESCE(T.remark, '') AS remark, CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END AS debit,
+		CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END AS credit, 0 AS balance, AT.id
+	` + vendorEntries + `
+	LEFT JOIN vendor_bill VB ON VB.transaction_id = T.id
+	LEFT JOIN payment_voucher PV ON PV.transaction_id = T.id
+	WHERE AT.account_id = ? AND V.id = ? AND T.posting_date BETWEEN ? AND ?
+	ORDER BY T.posting_date, AT.id
+`
+
+const VendorStatementLinesPage = `
+	SELECT AT.transaction_id, DATE_FORMAT(T.posting_date, '%Y-%m-%d') AS posting_date, COALESCE(VB.bill_number, PV.check_number, '') AS bill_number,
+		COALESCE(T.remark, '') AS remark, CASE WHEN AT.type = 'DR' THEN AT.amount ELSE 0 END AS debit,
+		CASE WHEN AT.type = 'CR' THEN AT.amount ELSE 0 END AS credit, 0 AS balance, AT.id
+	` + vendorEntries + `
+	LEFT JOIN vendor_bill VB ON VB.transaction_id = T.id
+	LEFT JOIN payment_voucher PV ON PV.transaction_id = T.id
+	WHERE AT.account_id = ? AND V.id = ? AND T.posting_date BETWEEN ? AND ?
+		AND (? IS NULL OR (T.posting_date, AT.id) > (?, ?))
+	ORDER BY T.posting_date, AT.id
+	LIMIT ?
+`
+
+const VendorStatementLinesCount = `
+	SELECT COUNT(*)
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND V.id = ? AND T.posting_date BETWEEN ? AND ?
+`
+
+const VendorBalanceThrough = `
+	SELECT COALESCE(SUM(CASE WHEN AT.type = 'CR' THEN AT.amount ELSE -AT.amount END), 0)
+	` + vendorEntries + `
+	WHERE AT.account_id = ? AND V.id = ? AND (T.posting_date, AT.id) <= (?, ?)
+`
+
+const UnassignedPayableEntries = bookEntries + `
+	LEFT JOIN vendor_transaction VT ON VT.transaction_id = T.id
+	WHERE AT.account_id = ? AND T.posting_date <= ? AND VT.vendor_id IS NULL
+	ORDER BY T.posting_date, AT.id
+`
@@ -0,0 +1,284 @@
+package scribe
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Receipt payment methods
+const (
+	PaymentCash     = "cash"
+	PaymentCheque   = "cheque"
+	PaymentCard     = "card"
+	PaymentTransfer = "transfer"
+)
+
+var paymentMethods = []string{PaymentCash, PaymentCheque, PaymentCard, PaymentTransfer}
+
+// CreateReceiptBook creates a receipt book numbering receipts from one.
+// The prefix is written before the receipt number.
+func (m *AccountModel) CreateReceiptBook(userID, name, prefix string) (int64, error) {
+	if name == "" {
+		return 0, errors.New("receipt book name is required")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	rb := mysequel.Table{
+		TableName: "receipt_book",
+		Columns:   []string{"name", "prefix", "next_number", "active"},
+		Vals:      []interface{}{name, prefix, 1, 1},
+		Tx:        tx,
+	}
+	bid, err := mysequel.Insert(rb)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, rb.TableName, bid, rb.Columns, rb.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	return bid, nil
+}
+
+// ReceiptBooks returns receipt books
+func (m *AccountModel) ReceiptBooks() ([]models.ReceiptBook, error) {
+	var res []models.ReceiptBook
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ReceiptBooks)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// nextReceiptNumber takes the next number of a receipt book. The book row
+// stays locked until the transaction ends so that numbers are sequential.
+func nextReceiptNumber(tx *sql.Tx, bookID string) (int, error) {
+	var prefix string
+	var next int
+	var active bool
+	err := tx.QueryRow(queries.ReceiptBookForUpdate, bookID).Scan(&prefix, &next, &active)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("receipt book not found")
+	} else if err != nil {
+		return 0, err
+	}
+	if !active {
+		return 0, errors.New("receipt book is closed")
+	}
+
+	_, err = mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "receipt_book",
+			Columns:   []string{"next_number"},
+			Vals:      []interface{}{next + 1},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{bookID},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return next, nil
+}
+
+// validateReceipt checks the payment method and that the entries add up to
+// the amount received
+func validateReceipt(r models.ReceiptVoucher, entries []models.PaymentVoucherEntry) error {
+	if !containsString(paymentMethods, r.PaymentMethod) {
+		return errors.New("invalid payment method")
+	}
+	if r.PaymentMethod == PaymentCheque {
+		if r.ChequeNumber == "" || r.ChequeBank == "" {
+			return errors.New("cheque number and bank are required for cheque receipts")
+		}
+		if r.ChequeDate != "" {
+			if _, err := time.Parse("2006-01-02", r.ChequeDate); err != nil {
+				return errors.New("invalid cheque date")
+			}
+		}
+	}
+
+	amount, err := parseAmount(r.Amount)
+	if err != nil {
+		return err
+	}
+	var credits int64
+	for _, e := range entries {
+		c, err := parseAmount(e.Amount)
+		if err != nil {
+			return err
+		}
+		credits += c
+	}
+	if amount == 0 || credits != amount {
+		return errors.New("receipt entries do not add up to the amount")
+	}
+	return nil
+}
+
+// ReceiptVoucher enters money received with a receipt numbered from the
+// receipt book and returns the transaction ID
+func (m *AccountModel) ReceiptVoucher(userID string, r models.ReceiptVoucher) (int64, error) {
+	if m.RequireApproval {
+		return 0, ErrApprovalRequired
+	}
+
+	err := validatePostingDate(r.PostingDate)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []models.PaymentVoucherEntry
+	if err := json.Unmarshal([]byte(r.Entries), &entries); err != nil {
+		return 0, errors.New("invalid receipt entries")
+	}
+	err = validateReceipt(r, entries)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	number, err := nextReceiptNumber(tx, r.ReceiptBookID)
+	if err != nil {
+		return 0, err
+	}
+
+	tid, err := CreateTransaction(tx, userID, r.PostingDate, r.ContractID, r.Remark)
+	if err != nil {
+		return 0, err
+	}
+
+	rv := mysequel.Table{
+		TableName: "receipt_voucher",
+		Columns:   []string{"transaction_id", "receipt_book_id", "receipt_number", "payer", "payment_method", "cheque_number", "cheque_bank", "cheque_date"},
+		Vals:      []interface{}{tid, r.ReceiptBookID, number, r.Payer, r.PaymentMethod, r.ChequeNumber, r.ChequeBank, r.ChequeDate},
+		Tx:        tx,
+	}
+	rid, err := mysequel.Insert(rv)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordCreate(tx, userID, rv.TableName, rid, rv.Columns, rv.Vals)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "account_transaction",
+		Columns:   []string{"transaction_id", "account_id", "type", "amount"},
+		Vals:      []interface{}{tid, r.ToAccountID, "DR", r.Amount},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "account_transaction",
+			Columns:   []string{"transaction_id", "account_id", "type", "amount"},
+			Vals:      []interface{}{tid, entry.Account, "CR", entry.Amount},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = appendTransactionLog(tx, tid, LogPost)
+	if err != nil {
+		return 0, err
+	}
+
+	return tid, nil
+}
+
+// ReceiptVouchers returns receipt vouchers
+func (m *AccountModel) ReceiptVouchers() ([]models.ReceiptVoucherList, error) {
+	var res []models.ReceiptVoucherList
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ReceiptVouchers)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReceiptVouchersPage returns a page of receipt vouchers, newest first
+func (m *AccountModel) ReceiptVouchersPage(cursor string, limit int) (models.ReceiptVoucherPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.ReceiptVoucherPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.ReceiptVouchersCount).Scan(&c.Total)
+		if err != nil {
+			return models.ReceiptVoucherPage{}, err
+		}
+	}
+
+	var res []models.ReceiptVoucherList
+	err = mysequel.QueryToStructs(&res, m.DB, queries.ReceiptVouchersPage, append(c.args(1), size+1)...)
+	if err != nil {
+		return models.ReceiptVoucherPage{}, err
+	}
+
+	page := models.ReceiptVoucherPage{ReceiptVouchers: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.ReceiptVouchers = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
+// ReceiptVoucherDetails returns receipt voucher details
+func (m *AccountModel) ReceiptVoucherDetails(rid int) (models.ReceiptVoucherSummary, error) {
+	var res models.ReceiptVoucherSummary
+	err := m.DB.QueryRow(queries.ReceiptVoucher, rid).Scan(&res.ReceiptNumber, &res.Payer, &res.PaymentMethod, &res.ChequeNumber, &res.ChequeBank, &res.ChequeDate, &res.Remark, &res.Account, &res.Datetime)
+	if err != nil {
+		return models.ReceiptVoucherSummary{}, err
+	}
+
+	err = mysequel.QueryToStructs(&res.ReceiptVoucherDetails, m.DB, queries.ReceiptVoucherDetails, rid)
+	if err != nil {
+		return models.ReceiptVoucherSummary{}, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,462 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// errNoReceivableAccount is returned by the receivables subledger when no
+// control account is set
+var errNoReceivableAccount = errors.New("receivable control account is not set")
+
+// openEnded includes every allocation when reading current open items
+const openEnded = "9999-12-31"
+
+// ContractBalances returns the receivable balance of every contract on the
+// posting date
+func (m *AccountModel) ContractBalances(postingDate string) ([]models.ContractBalance, error) {
+	if m.ReceivableAccountID == 0 {
+		return nil, errNoReceivableAccount
+	}
+
+	var res []models.ContractBalance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ContractBalances, m.ReceivableAccountID, postingDate, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ContractBalancesPage returns a page of ContractBalances in contract order
+func (m *AccountModel) ContractBalancesPage(postingDate, cursor string, limit int) (models.ContractBalancePage, error) {
+	if m.ReceivableAccountID == 0 {
+		return models.ContractBalancePage{}, errNoReceivableAccount
+	}
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.ContractBalancePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.ContractBalancesCount, m.ReceivableAccountID, postingDate).Scan(&c.Total)
+		if err != nil {
+			return models.ContractBalancePage{}, err
+		}
+	}
+
+	var res []models.ContractBalance
+	args := append([]interface{}{m.ReceivableAccountID, postingDate}, c.args(1)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.ContractBalancesPage, append(args, size+1)...)
+	if err != nil {
+		return models.ContractBalancePage{}, err
+	}
+
+	page := models.ContractBalancePage{Balances: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Balances = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ContractID)
+	}
+	return page, nil
+}
+
+// ContractBalance returns the receivable balance of a contract on the
+// posting date
+func (m *AccountModel) ContractBalance(contractID, postingDate string) (models.ContractBalance, error) {
+	if m.ReceivableAccountID == 0 {
+		return models.ContractBalance{}, errNoReceivableAccount
+	}
+
+	var res []models.ContractBalance
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ContractBalances, m.ReceivableAccountID, postingDate, contractID, contractID)
+	if err != nil {
+		return models.ContractBalance{}, err
+	}
+	if len(res) == 0 {
+		return models.ContractBalance{ContractID: contractID}, nil
+	}
+
+	return res[0], nil
+}
+
+// receivableItems reads the receivable items of a contract, or of every
+// contract when contractID is empty, as they stood on the posting date
+func receivableItems(q mysequel.QueryRunner, query string, accountID int, contractID, postingDate string) ([]models.ReceivableItem, error) {
+	contract := mysequel.NewNullString(contractID)
+	var res []models.ReceivableItem
+	err := mysequel.QueryToStructs(&res, q, query, postingDate, postingDate, accountID, postingDate, contract, contract)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// receivableItemsPage reads a page of receivable items in contract and
+// posting date order with the page and count queries given
+func receivableItemsPage(db *sql.DB, query, countQuery string, accountID int, contractID, postingDate, cursor string, limit int) (models.ReceivableItemPage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.ReceivableItemPage{}, err
+	}
+	size := pageSize(limit)
+	contract := mysequel.NewNullString(contractID)
+	filter := []interface{}{postingDate, postingDate, accountID, postingDate, contract, contract}
+
+	if c.first() {
+		err = db.QueryRow(countQuery, filter...).Scan(&c.Total)
+		if err != nil {
+			return models.ReceivableItemPage{}, err
+		}
+	}
+
+	var res []models.ReceivableItem
+	args := append(filter, c.args(3)...)
+	err = mysequel.QueryToStructs(&res, db, query, append(args, size+1)...)
+	if err != nil {
+		return models.ReceivableItemPage{}, err
+	}
+
+	page := models.ReceivableItemPage{Items: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Items = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.ContractID, last.PostingDate, last.EntryID)
+	}
+	return page, nil
+}
+
+// ContractLedger returns every receivable item of a contract up to the
+// posting date with the amounts allocated by then
+func (m *AccountModel) ContractLedger(contractID, postingDate string) ([]models.ReceivableItem, error) {
+	if m.ReceivableAccountID == 0 {
+		return nil, errNoReceivableAccount
+	}
+
+	return receivableItems(m.DB, queries.ReceivableItems, m.ReceivableAccountID, contractID, postingDate)
+}
+
+// ContractLedgerPage returns a page of ContractLedger
+func (m *AccountModel) ContractLedgerPage(contractID, postingDate, cursor string, limit int) (models.ReceivableItemPage, error) {
+	if m.ReceivableAccountID == 0 {
+		return models.ReceivableItemPage{}, errNoReceivableAccount
+	}
+
+	return receivableItemsPage(m.DB, queries.ReceivableItemsPage, queries.ReceivableItemsCount, m.ReceivableAccountID, contractID, postingDate, cursor, limit)
+}
+
+// OpenReceivables returns the charges and credits of a contract that were
+// not fully allocated on the posting date. All contracts are included when
+// contractID is empty.
+func (m *AccountModel) OpenReceivables(contractID, postingDate string) ([]models.ReceivableItem, error) {
+	if m.ReceivableAccountID == 0 {
+		return nil, errNoReceivableAccount
+	}
+
+	return receivableItems(m.DB, queries.OpenReceivableItems, m.ReceivableAccountID, contractID, postingDate)
+}
+
+// OpenReceivablesPage returns a page of OpenReceivables
+func (m *AccountModel) OpenReceivablesPage(contractID, postingDate, cursor string, limit int) (models.ReceivableItemPage, error) {
+	if m.ReceivableAccountID == 0 {
+		return models.ReceivableItemPage{}, errNoReceivableAccount
+	}
+
+	return receivableItemsPage(m.DB, queries.OpenReceivableItemsPage, queries.OpenReceivableItemsCount, m.ReceivableAccountID, contractID, postingDate, cursor, limit)
+}
+
+// lockContractReceivables locks the control account entries of a contract
+// so that its allocations change one at a time, and returns its current
+// open items
+func lockContractReceivables(tx *sql.Tx, accountID int, contractID string) ([]models.ReceivableItem, error) {
+	rows, err := tx.Query(queries.LockContractReceivables, accountID, contractID)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return receivableItems(tx, queries.OpenReceivableItems, accountID, contractID, openEnded)
+}
+
+// insertAllocation records an allocation of a credit to a charge
+func insertAllocation(tx *sql.Tx, userID string, a models.ReceivableAllocation) error {
+	_, err := mysequel.Insert(mysequel.Table{
+		TableName: "receivable_allocation",
+		Columns:   []string{"charge_entry_id", "credit_entry_id", "amount", "user_id", "datetime"},
+		Vals:      []interface{}{a.ChargeEntryID, a.CreditEntryID, formatAmount(toCents(a.Amount)), userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	return err
+}
+
+// AllocateReceipt allocates a receipt or credit to specific charges of the
+// same contract. Neither may be allocated beyond its amount.
+func (m *AccountModel) AllocateReceipt(userID string, creditEntryID int, allocations []models.ReceivableAllocation) error {
+	if m.ReceivableAccountID == 0 {
+		return errNoReceivableAccount
+	}
+	if len(allocations) == 0 {
+		return errors.New("no allocations")
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var contractID string
+	err = tx.QueryRow(queries.ReceivableEntryContract, creditEntryID, m.ReceivableAccountID).Scan(&contractID)
+	if err == sql.ErrNoRows {
+		err = errors.New("entry is not on the receivable control account")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if contractID == "" {
+		err = errors.New("entry does not belong to a contract")
+		return err
+	}
+
+	items, err := lockContractReceivables(tx, m.ReceivableAccountID, contractID)
+	if err != nil {
+		return err
+	}
+	open := make(map[int]models.ReceivableItem, len(items))
+	for _, i := range items {
+		open[i.EntryID] = i
+	}
+
+	credit, ok := open[creditEntryID]
+	if !ok || credit.Type != "CR" {
+		err = errors.New("entry is not an open receipt or credit")
+		return err
+	}
+	remaining := toCents(credit.Outstanding)
+
+	for _, a := range allocations {
+		charge, ok := open[a.ChargeEntryID]
+		if !ok || charge.Type != "DR" {
+			err = errors.New("allocation is not to an open charge of the contract")
+			return err
+		}
+		amount := toCents(a.Amount)
+		if amount <= 0 {
+			err = errors.New("allocation amount must be positive")
+			return err
+		}
+		if amount > toCents(charge.Outstanding) || amount > remaining {
+			err = errors.New("allocation exceeds the outstanding amount")
+			return err
+		}
+		charge.Outstanding = fromCents(toCents(charge.Outstanding) - amount)
+		open[a.ChargeEntryID] = charge
+		remaining -= amount
+
+		a.CreditEntryID = creditEntryID
+		err = insertAllocation(tx, userID, a)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fifoAllocations allocates open credits to the oldest open charges. The
+// items must be in posting order.
+func fifoAllocations(items []models.ReceivableItem) []models.ReceivableAllocation {
+	var charges []models.ReceivableItem
+	for _, i := range items {
+		if i.Type == "DR" && toCents(i.Outstanding) > 0 {
+			charges = append(charges, i)
+		}
+	}
+
+	var res []models.ReceivableAllocation
+	c := 0
+	for _, i := range items {
+		if i.Type != "CR" {
+			continue
+		}
+		remaining := toCents(i.Outstanding)
+		for remaining > 0 && c < len(charges) {
+			owed := toCents(charges[c].Outstanding)
+			amount := owed
+			if remaining < amount {
+				amount = remaining
+			}
+			res = append(res, models.ReceivableAllocation{ChargeEntryID: charges[c].EntryID, CreditEntryID: i.EntryID, Amount: fromCents(amount)})
+			remaining -= amount
+			charges[c].Outstanding = fromCents(owed - amount)
+			if owed == amount {
+				c++
+			}
+		}
+	}
+	return res
+}
+
+// AutoAllocate allocates the unallocated receipts and credits of a
+// contract to its oldest outstanding charges and returns the allocations
+// made
+func (m *AccountModel) AutoAllocate(userID, contractID string) ([]models.ReceivableAllocation, error) {
+	if m.ReceivableAccountID == 0 {
+		return nil, errNoReceivableAccount
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	items, err := lockContractReceivables(tx, m.ReceivableAccountID, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := fifoAllocations(items)
+	for _, a := range res {
+		err = insertAllocation(tx, userID, a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// ReceivableAllocations returns the allocations of a contract
+func (m *AccountModel) ReceivableAllocations(contractID string) ([]models.ReceivableAllocation, error) {
+	var res []models.ReceivableAllocation
+	err := mysequel.QueryToStructs(&res, m.DB, queries.ReceivableAllocations, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ReceivableAllocationsPage returns a page of the allocations of a contract
+func (m *AccountModel) ReceivableAllocationsPage(contractID, cursor string, limit int) (models.ReceivableAllocationPage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.ReceivableAllocationPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.ReceivableAllocationsCount, contractID).Scan(&c.Total)
+		if err != nil {
+			return models.ReceivableAllocationPage{}, err
+		}
+	}
+
+	var res []models.ReceivableAllocation
+	args := append([]interface{}{contractID}, c.args(1)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.ReceivableAllocationsPage, append(args, size+1)...)
+	if err != nil {
+		return models.ReceivableAllocationPage{}, err
+	}
+
+	page := models.ReceivableAllocationPage{Allocations: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Allocations = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
+// DeleteAllocation removes an allocation, reopening its charge and credit
+func (m *AccountModel) DeleteAllocation(allocationID int64) error {
+	if m.ReceivableAccountID == 0 {
+		return errNoReceivableAccount
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var contractID string
+	err = tx.QueryRow(queries.ReceivableAllocationContract, allocationID).Scan(&contractID)
+	if err != nil {
+		return err
+	}
+
+	_, err = lockContractReceivables(tx, m.ReceivableAccountID, contractID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(queries.DeleteReceivableAllocation, allocationID)
+	return err
+}
+
+// ReceivablesReconciliation checks that the contract balances add up to
+// the balance of the receivable control account in the trial balance on
+// the posting date
+func (m *AccountModel) ReceivablesReconciliation(postingDate string) (models.ReceivablesReconciliation, error) {
+	res := models.ReceivablesReconciliation{Date: postingDate, AccountID: m.ReceivableAccountID}
+	if m.ReceivableAccountID == 0 {
+		return res, errNoReceivableAccount
+	}
+
+	trial, err := m.TrialBalance(postingDate)
+	if err != nil {
+		return res, err
+	}
+	var control int64
+	for _, e := range trial {
+		if e.ID == m.ReceivableAccountID {
+			control = toCents(e.Debit) - toCents(e.Credit)
+		}
+	}
+
+	res.Contracts, err = m.ContractBalances(postingDate)
+	if err != nil {
+		return res, err
+	}
+	var subledger int64
+	for _, c := range res.Contracts {
+		subledger += toCents(c.Balance)
+	}
+
+	err = mysequel.QueryToStructs(&res.Unassigned, m.DB, queries.UnassignedControlEntries, m.ReceivableAccountID, postingDate)
+	if err != nil {
+		return res, err
+	}
+
+	res.ControlBalance = fromCents(control)
+	res.SubledgerBalance = fromCents(subledger)
+	res.Difference = fromCents(control - subledger)
+	res.Reconciled = control == subledger
+	return res, nil
+}
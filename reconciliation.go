@@ -0,0 +1,642 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+	"github.com/ssrdive/scribe/statement"
+)
+
+// Reconciliation session states
+const (
+	StatementOpen   = "open"
+	StatementClosed = "closed"
+)
+
+// How statement lines and book entries were matched
+const (
+	MatchAuto   = "auto"
+	MatchManual = "manual"
+	MatchRule   = "rule"
+)
+
+// DefaultMatchTolerance matches equal amounts up to three days apart
+var DefaultMatchTolerance = models.MatchTolerance{Days: 3}
+
+// ImportStatement stores a bank statement and opens a reconciliation
+// session for its period. Statement periods of an account may not overlap
+// and the lines must add up from the opening to the closing balance.
+func (m *AccountModel) ImportStatement(userID string, s models.BankStatement) (int64, error) {
+	start, err := time.Parse("2006-01-02", s.StartDate)
+	if err != nil {
+		return 0, errors.New("invalid start date")
+	}
+	end, err := time.Parse("2006-01-02", s.EndDate)
+	if err != nil {
+		return 0, errors.New("invalid end date")
+	}
+	if end.Before(start) {
+		return 0, errors.New("end date is before the start date")
+	}
+
+	balance := toCents(s.OpeningBalance)
+	for i, l := range s.Lines {
+		d, err := time.Parse("2006-01-02", l.Date)
+		if err != nil {
+			return 0, fmt.Errorf("line %d has an invalid date", i+1)
+		}
+		if d.Before(start) || d.After(end) {
+			return 0, fmt.Errorf("line %d is outside the statement period", i+1)
+		}
+		balance += toCents(l.Amount)
+	}
+	if balance != toCents(s.ClosingBalance) {
+		return 0, fmt.Errorf("lines add up to a closing balance of %s instead of %s", formatAmount(balance), formatAmount(toCents(s.ClosingBalance)))
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var overlaps int
+	err = tx.QueryRow(queries.BankStatementOverlaps, s.AccountID, s.EndDate, s.StartDate).Scan(&overlaps)
+	if err != nil {
+		return 0, err
+	}
+	if overlaps > 0 {
+		err = errors.New("statement period overlaps an imported statement")
+		return 0, err
+	}
+
+	sid, err := mysequel.Insert(mysequel.Table{
+		TableName: "bank_statement",
+		Columns:   []string{"account_id", "start_date", "end_date", "opening_balance", "closing_balance", "status", "user_id", "datetime"},
+		Vals:      []interface{}{s.AccountID, s.StartDate, s.EndDate, formatAmount(toCents(s.OpeningBalance)), formatAmount(toCents(s.ClosingBalance)), StatementOpen, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, l := range s.Lines {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "bank_statement_line",
+			Columns:   []string{"bank_statement_id", "line_date", "description", "counterparty", "reference", "cheque_number", "amount"},
+			Vals:      []interface{}{sid, l.Date, l.Description, l.Counterparty, l.Reference, l.ChequeNumber, formatAmount(toCents(l.Amount))},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return sid, nil
+}
+
+// ImportParsedStatement imports a statement read from a bank file by the
+// statement package into the bank account accountID
+func (m *AccountModel) ImportParsedStatement(userID string, accountID int, s statement.Statement) (int64, error) {
+	bs := s.BankStatement
+	bs.AccountID = accountID
+	return m.ImportStatement(userID, bs)
+}
+
+// BankStatements returns imported statements of an account, or of all
+// accounts when accountID is empty, with their matching progress
+func (m *AccountModel) BankStatements(accountID string) ([]models.BankStatementDetails, error) {
+	aid := mysequel.NewNullString(accountID)
+
+	var res []models.BankStatementDetails
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BankStatements, aid, aid)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// BankStatementsPage returns a page of BankStatements in account and
+// start date order
+func (m *AccountModel) BankStatementsPage(accountID, cursor string, limit int) (models.BankStatementPage, error) {
+	c, err := decodeCursor(cursor, 3)
+	if err != nil {
+		return models.BankStatementPage{}, err
+	}
+	size := pageSize(limit)
+	aid := mysequel.NewNullString(accountID)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.BankStatementsCount, aid, aid).Scan(&c.Total)
+		if err != nil {
+			return models.BankStatementPage{}, err
+		}
+	}
+
+	var res []models.BankStatementDetails
+	args := append([]interface{}{aid, aid}, c.args(3)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.BankStatementsPage, append(args, size+1)...)
+	if err != nil {
+		return models.BankStatementPage{}, err
+	}
+
+	page := models.BankStatementPage{Statements: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Statements = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.AccountID, last.StartDate, last.ID)
+	}
+	return page, nil
+}
+
+// BankStatementLines returns the lines of a statement
+func (m *AccountModel) BankStatementLines(statementID int64) ([]models.BankStatementLine, error) {
+	var res []models.BankStatementLine
+	err := mysequel.QueryToStructs(&res, m.DB, queries.BankStatementLines, statementID)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// BankStatementLinesPage returns a page of the lines of a statement
+func (m *AccountModel) BankStatementLinesPage(statementID int64, cursor string, limit int) (models.BankStatementLinePage, error) {
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.BankStatementLinePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.BankStatementLinesCount, statementID).Scan(&c.Total)
+		if err != nil {
+			return models.BankStatementLinePage{}, err
+		}
+	}
+
+	var res []models.BankStatementLine
+	args := append([]interface{}{statementID}, c.args(2)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.BankStatementLinesPage, append(args, size+1)...)
+	if err != nil {
+		return models.BankStatementLinePage{}, err
+	}
+
+	page := models.BankStatementLinePage{Lines: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Lines = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.Date, last.ID)
+	}
+	return page, nil
+}
+
+// UnmatchedBookEntries returns the entries of a bank account up to a date
+// that are not matched to a statement line
+func (m *AccountModel) UnmatchedBookEntries(accountID int, date string) ([]models.BookEntry, error) {
+	var res []models.BookEntry
+	err := mysequel.QueryToStructs(&res, m.DB, queries.UnmatchedBookEntries, accountID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// UnmatchedBookEntriesPage returns a page of UnmatchedBookEntries
+func (m *AccountModel) UnmatchedBookEntriesPage(accountID int, date, cursor string, limit int) (models.BookEntryPage, error) {
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.BookEntryPage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.UnmatchedBookEntriesCount, accountID, date).Scan(&c.Total)
+		if err != nil {
+			return models.BookEntryPage{}, err
+		}
+	}
+
+	var res []models.BookEntry
+	args := append([]interface{}{accountID, date}, c.args(2)...)
+	err = mysequel.QueryToStructs(&res, m.DB, queries.UnmatchedBookEntriesPage, append(args, size+1)...)
+	if err != nil {
+		return models.BookEntryPage{}, err
+	}
+
+	page := models.BookEntryPage{Entries: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Entries = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.PostingDate, last.ID)
+	}
+	return page, nil
+}
+
+type statementSession struct {
+	accountID      int
+	startDate      string
+	endDate        string
+	closingBalance float64
+}
+
+// lockStatement locks an open reconciliation session
+func lockStatement(tx *sql.Tx, statementID int64) (statementSession, error) {
+	var s statementSession
+	var status string
+	err := tx.QueryRow(queries.BankStatementForUpdate, statementID).Scan(&s.accountID, &s.startDate, &s.endDate, &s.closingBalance, &status)
+	if err != nil {
+		return s, err
+	}
+	if status != StatementOpen {
+		return s, errors.New("reconciliation is closed")
+	}
+	return s, nil
+}
+
+// statementTx runs fn with the reconciliation session locked
+func (m *AccountModel) statementTx(statementID int64, fn func(tx *sql.Tx, s statementSession) error) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	s, err := lockStatement(tx, statementID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx, s)
+	return err
+}
+
+// insertMatch matches statement lines with book entries. Callers hold the
+// statement lock for the lines and read the entries with
+// UnmatchedBookEntriesForUpdate, so that sessions of the same account
+// cannot match an entry twice.
+func insertMatch(tx *sql.Tx, userID, method string, lineIDs, entryIDs []int) (int64, error) {
+	mid, err := mysequel.Insert(mysequel.Table{
+		TableName: "bank_match",
+		Columns:   []string{"method", "user_id", "datetime"},
+		Vals:      []interface{}{method, userID, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range lineIDs {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "bank_match_line",
+			Columns:   []string{"bank_match_id", "bank_statement_line_id"},
+			Vals:      []interface{}{mid, id},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, id := range entryIDs {
+		_, err = mysequel.Insert(mysequel.Table{
+			TableName: "bank_match_entry",
+			Columns:   []string{"bank_match_id", "account_transaction_id"},
+			Vals:      []interface{}{mid, id},
+			Tx:        tx,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return mid, nil
+}
+
+// sameCheque compares cheque numbers ignoring leading zeros
+func sameCheque(a, b string) bool {
+	a, b = strings.TrimLeft(strings.TrimSpace(a), "0"), strings.TrimLeft(strings.TrimSpace(b), "0")
+	return a != "" && a == b
+}
+
+func daysApart(a, b string) int {
+	x, _ := time.Parse("2006-01-02", a)
+	y, _ := time.Parse("2006-01-02", b)
+	d := int(x.Sub(y).Hours() / 24)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func absCents(c int64) int64 {
+	if c < 0 {
+		return -c
+	}
+	return c
+}
+
+// autoMatches pairs statement lines with book entries one to one. Lines
+// with a cheque number are matched to the entry with the same cheque
+// number first. Other lines take the closest dated entry within the
+// tolerances, preferring the closest amount and then the earliest entry.
+func autoMatches(lines []models.BankStatementLine, entries []models.BookEntry, tol models.MatchTolerance) [][2]int {
+	amountTol := toCents(tol.Amount)
+	used := make(map[int]bool)
+	matched := make(map[int]bool)
+	var pairs [][2]int
+
+	for i, l := range lines {
+		if l.ChequeNumber == "" {
+			continue
+		}
+		for j, e := range entries {
+			if !used[j] && sameCheque(l.ChequeNumber, e.ChequeNumber) && absCents(toCents(l.Amount)-toCents(e.Amount)) <= amountTol {
+				used[j], matched[i] = true, true
+				pairs = append(pairs, [2]int{i, j})
+				break
+			}
+		}
+	}
+
+	for i, l := range lines {
+		if matched[i] {
+			continue
+		}
+		best := -1
+		var bestDays int
+		var bestDiff int64
+		for j, e := range entries {
+			if used[j] {
+				continue
+			}
+			diff := absCents(toCents(l.Amount) - toCents(e.Amount))
+			days := daysApart(l.Date, e.PostingDate)
+			if diff > amountTol || days > tol.Days {
+				continue
+			}
+			if best < 0 || days < bestDays || (days == bestDays && diff < bestDiff) {
+				best, bestDays, bestDiff = j, days, diff
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			pairs = append(pairs, [2]int{i, best})
+		}
+	}
+	return pairs
+}
+
+// AutoMatch matches the unmatched lines of a statement one to one with
+// unmatched entries of the bank account by cheque number, amount and date
+// within the tolerances and returns the number of matches made
+func (m *AccountModel) AutoMatch(userID string, statementID int64, tol models.MatchTolerance) (int, error) {
+	if tol.Days < 0 || tol.Amount < 0 {
+		return 0, errors.New("tolerances cannot be negative")
+	}
+
+	var n int
+	err := m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+		var lines []models.BankStatementLine
+		err := mysequel.QueryToStructs(&lines, tx, queries.UnmatchedStatementLines, statementID)
+		if err != nil {
+			return err
+		}
+
+		end, _ := time.Parse("2006-01-02", s.endDate)
+		var entries []models.BookEntry
+		err = mysequel.QueryToStructs(&entries, tx, queries.UnmatchedBookEntriesForUpdate, s.accountID, end.AddDate(0, 0, tol.Days).Format("2006-01-02"))
+		if err != nil {
+			return err
+		}
+
+		for _, p := range autoMatches(lines, entries, tol) {
+			_, err = insertMatch(tx, userID, MatchAuto, []int{lines[p[0]].ID}, []int{entries[p[1]].ID})
+			if err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// Match manually matches one or more statement lines with one or more
+// book entries of the bank account. The lines and entries must add up to
+// the same amount.
+func (m *AccountModel) Match(userID string, statementID int64, lineIDs, entryIDs []int) (int64, error) {
+	if len(lineIDs) == 0 || len(entryIDs) == 0 {
+		return 0, errors.New("select at least one statement line and one book entry")
+	}
+
+	var mid int64
+	err := m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+		var lines []models.BankStatementLine
+		err := mysequel.QueryToStructs(&lines, tx, queries.UnmatchedStatementLines, statementID)
+		if err != nil {
+			return err
+		}
+		unmatchedLines := make(map[int]int64)
+		for _, l := range lines {
+			unmatchedLines[l.ID] = toCents(l.Amount)
+		}
+
+		var entries []models.BookEntry
+		err = mysequel.QueryToStructs(&entries, tx, queries.UnmatchedBookEntriesForUpdate, s.accountID, "9999-12-31")
+		if err != nil {
+			return err
+		}
+		unmatchedEntries := make(map[int]int64)
+		for _, e := range entries {
+			unmatchedEntries[e.ID] = toCents(e.Amount)
+		}
+
+		var bank, book int64
+		for _, id := range lineIDs {
+			amount, ok := unmatchedLines[id]
+			if !ok {
+				return fmt.Errorf("statement line %d is not an unmatched line of the statement", id)
+			}
+			bank += amount
+		}
+		for _, id := range entryIDs {
+			amount, ok := unmatchedEntries[id]
+			if !ok {
+				return fmt.Errorf("entry %d is not an unmatched entry of the bank account", id)
+			}
+			book += amount
+		}
+		if bank != book {
+			return fmt.Errorf("statement lines total %s but book entries total %s", formatAmount(bank), formatAmount(book))
+		}
+
+		mid, err = insertMatch(tx, userID, MatchManual, lineIDs, entryIDs)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return mid, nil
+}
+
+// Unmatch removes a match while its reconciliation is open
+func (m *AccountModel) Unmatch(matchID int64) error {
+	var statementID int64
+	err := m.DB.QueryRow(queries.BankMatchStatement, matchID).Scan(&statementID)
+	if err != nil {
+		return err
+	}
+
+	return m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+		for _, q := range []string{queries.DeleteBankMatchLines, queries.DeleteBankMatchEntries, queries.DeleteBankMatch} {
+			if _, err := tx.Exec(q, matchID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	mysequel.QueryRunner
+	QueryRow(string, ...interface{}) *sql.Row
+}
+
+// reconciliation builds the reconciliation report of a session. Book
+// entries dated before the first statement of the account are covered by
+// its opening balance and are never reported as outstanding.
+func reconciliation(q queryRower, statementID int64, s statementSession) (models.BankReconciliation, error) {
+	r := models.BankReconciliation{
+		StatementID:       int(statementID),
+		AccountID:         s.accountID,
+		EndDate:           s.endDate,
+		StatementBalance:  s.closingBalance,
+		DepositsInTransit: []models.BookEntry{},
+		UnclearedCheques:  []models.BookEntry{},
+	}
+
+	var cutoff string
+	var opening float64
+	err := q.QueryRow(queries.FirstBankStatement, s.accountID).Scan(&cutoff, &opening)
+	if err != nil {
+		return r, err
+	}
+	var bookAtCutoff, matchedBefore float64
+	err = q.QueryRow(queries.LedgerOpeningBalance, s.accountID, cutoff).Scan(&bookAtCutoff)
+	if err != nil {
+		return r, err
+	}
+	err = q.QueryRow(queries.MatchedBookEntriesBefore, s.accountID, cutoff, s.endDate).Scan(&matchedBefore)
+	if err != nil {
+		return r, err
+	}
+	openingDifference := toCents(opening) - toCents(bookAtCutoff) + toCents(matchedBefore)
+
+	var outstanding []models.BookEntry
+	err = mysequel.QueryToStructs(&outstanding, q, queries.OutstandingBookEntries, s.accountID, s.endDate, cutoff, s.endDate)
+	if err != nil {
+		return r, err
+	}
+	var deposits, cheques int64
+	for _, e := range outstanding {
+		if e.Amount >= 0 {
+			r.DepositsInTransit = append(r.DepositsInTransit, e)
+			deposits += toCents(e.Amount)
+		} else {
+			r.UnclearedCheques = append(r.UnclearedCheques, e)
+			cheques -= toCents(e.Amount)
+		}
+	}
+
+	err = mysequel.QueryToStructs(&r.UnrecordedItems, q, queries.UnmatchedStatementLines, statementID)
+	if err != nil {
+		return r, err
+	}
+	var unrecorded int64
+	for _, l := range r.UnrecordedItems {
+		unrecorded += toCents(l.Amount)
+	}
+	if r.UnrecordedItems == nil {
+		r.UnrecordedItems = []models.BankStatementLine{}
+	}
+
+	end, _ := time.Parse("2006-01-02", s.endDate)
+	var book float64
+	err = q.QueryRow(queries.LedgerOpeningBalance, s.accountID, end.AddDate(0, 0, 1).Format("2006-01-02")).Scan(&book)
+	if err != nil {
+		return r, err
+	}
+
+	bank := toCents(s.closingBalance) + deposits - cheques
+	adjustedBook := toCents(book) + openingDifference + unrecorded
+	r.TotalDepositsInTransit = fromCents(deposits)
+	r.TotalUnclearedCheques = fromCents(cheques)
+	r.AdjustedBankBalance = fromCents(bank)
+	r.BookBalance = book
+	r.OpeningDifference = fromCents(openingDifference)
+	r.TotalUnrecordedItems = fromCents(unrecorded)
+	r.AdjustedBookBalance = fromCents(adjustedBook)
+	r.Difference = fromCents(bank - adjustedBook)
+	return r, nil
+}
+
+// Reconciliation returns the bank reconciliation at the end of a
+// statement period with uncleared cheques, deposits in transit and
+// statement lines not yet recorded in the books
+func (m *AccountModel) Reconciliation(statementID int64) (models.BankReconciliation, error) {
+	var s statementSession
+	var status string
+	err := m.DB.QueryRow(queries.BankStatement, statementID).Scan(&s.accountID, &s.startDate, &s.endDate, &s.closingBalance, &status)
+	if err != nil {
+		return models.BankReconciliation{}, err
+	}
+
+	return reconciliation(m.DB, statementID, s)
+}
+
+// CloseReconciliation closes a reconciliation session once the statement
+// and book balances reconcile and every statement line is matched. Matches
+// of a closed session cannot change.
+func (m *AccountModel) CloseReconciliation(userID string, statementID int64) error {
+	return m.statementTx(statementID, func(tx *sql.Tx, s statementSession) error {
+		r, err := reconciliation(tx, statementID, s)
+		if err != nil {
+			return err
+		}
+		if toCents(r.Difference) != 0 {
+			return fmt.Errorf("reconciliation differs by %s", formatAmount(toCents(r.Difference)))
+		}
+		if len(r.UnrecordedItems) > 0 {
+			return fmt.Errorf("%d statement lines are not recorded in the books", len(r.UnrecordedItems))
+		}
+
+		return updateRecord(tx, userID, "bank_statement", statementID, []string{"status"}, []interface{}{StatementClosed})
+	})
+}
@@ -0,0 +1,173 @@
+package scribe
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+	"github.com/ssrdive/scribe/statement"
+)
+
+func TestAutoMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []models.BankStatementLine
+		entries []models.BookEntry
+		tol     models.MatchTolerance
+		want    [][2]int
+	}{
+		{
+			name:  "cheque number before date",
+			lines: []models.BankStatementLine{{Date: "2024-01-10", ChequeNumber: "000123", Amount: -100}},
+			entries: []models.BookEntry{
+				{PostingDate: "2024-01-10", Amount: -100},
+				{PostingDate: "2024-01-20", ChequeNumber: "123", Amount: -100},
+			},
+			tol:  models.MatchTolerance{Days: 3},
+			want: [][2]int{{0, 1}},
+		},
+		{
+			name: "cheque lines are matched first",
+			lines: []models.BankStatementLine{
+				{Date: "2024-01-10", Amount: 100},
+				{Date: "2024-01-10", ChequeNumber: "5", Amount: 100},
+			},
+			entries: []models.BookEntry{{PostingDate: "2024-01-10", ChequeNumber: "5", Amount: 100}},
+			want:    [][2]int{{1, 0}},
+		},
+		{
+			name:  "cheque amount outside tolerance falls back to date",
+			lines: []models.BankStatementLine{{Date: "2024-01-10", ChequeNumber: "7", Amount: -100}},
+			entries: []models.BookEntry{
+				{PostingDate: "2024-01-10", ChequeNumber: "7", Amount: -105},
+				{PostingDate: "2024-01-11", Amount: -100},
+			},
+			tol:  models.MatchTolerance{Days: 2},
+			want: [][2]int{{0, 1}},
+		},
+		{
+			name:  "fewer days before smaller amount difference",
+			lines: []models.BankStatementLine{{Date: "2024-01-10", Amount: 100}},
+			entries: []models.BookEntry{
+				{PostingDate: "2024-01-14", Amount: 100},
+				{PostingDate: "2024-01-11", Amount: 101},
+			},
+			tol:  models.MatchTolerance{Days: 5, Amount: 2},
+			want: [][2]int{{0, 1}},
+		},
+		{
+			name:  "smaller amount difference on the same day",
+			lines: []models.BankStatementLine{{Date: "2024-01-10", Amount: 100}},
+			entries: []models.BookEntry{
+				{PostingDate: "2024-01-10", Amount: 101.5},
+				{PostingDate: "2024-01-10", Amount: 100.25},
+			},
+			tol:  models.MatchTolerance{Amount: 2},
+			want: [][2]int{{0, 1}},
+		},
+		{
+			name: "each entry matched once",
+			lines: []models.BankStatementLine{
+				{Date: "2024-01-10", Amount: 100},
+				{Date: "2024-01-10", Amount: 100},
+			},
+			entries: []models.BookEntry{{PostingDate: "2024-01-10", Amount: 100}},
+			want:    [][2]int{{0, 0}},
+		},
+		{
+			name:  "outside tolerances",
+			lines: []models.BankStatementLine{{Date: "2024-01-10", Amount: 100}},
+			entries: []models.BookEntry{
+				{PostingDate: "2024-01-14", Amount: 100},
+				{PostingDate: "2024-01-10", Amount: 100.01},
+			},
+			tol: models.MatchTolerance{Days: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := autoMatches(tt.lines, tt.entries, tt.tol)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const mt940Statement = `:20:STMT1
+:25:7010/1002003
+:28C:1/1
+:60F:C240101LKR2000,00
+:61:2401050105C1500,00NTRFNONREF
+:86:ACME LTD Invoice 42
+:61:2401100110D250,50NCHK000123
+:86:CITY POWER
+:62F:C240131LKR3249,50
+-
+`
+
+func TestImportParsedStatement(t *testing.T) {
+	parse := func(t *testing.T) statement.Statement {
+		statements, err := statement.Parse(strings.NewReader(mt940Statement), statement.FormatMT940)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statements) != 1 {
+			t.Fatalf("got %d statements, want 1", len(statements))
+		}
+		return statements[0]
+	}
+
+	t.Run("imports", func(t *testing.T) {
+		db, stub := newStubDB(t, map[string][][]driver.Value{
+			queries.BankStatementOverlaps: {{int64(0)}},
+		})
+		m := &AccountModel{DB: db}
+		s := parse(t)
+		if _, err := m.ImportParsedStatement("1", 12, s); err != nil {
+			t.Fatal(err)
+		}
+		statements := stub.committedTo("`bank_statement`")
+		if len(statements) != 1 {
+			t.Fatalf("got %d statements, want 1", len(statements))
+		}
+		if args := statements[0].args; args[0] != "12" || args[1] != s.StartDate || args[2] != s.EndDate {
+			t.Errorf("got statement %v, want account 12 from %s to %s", args, s.StartDate, s.EndDate)
+		}
+		if lines := stub.committedTo("`bank_statement_line`"); len(lines) != 2 {
+			t.Errorf("got %d lines, want 2", len(lines))
+		}
+	})
+
+	t.Run("overlap", func(t *testing.T) {
+		db, stub := newStubDB(t, map[string][][]driver.Value{
+			queries.BankStatementOverlaps: {{int64(1)}},
+		})
+		m := &AccountModel{DB: db}
+		_, err := m.ImportParsedStatement("1", 12, parse(t))
+		if err == nil || !strings.Contains(err.Error(), "overlaps") {
+			t.Fatalf("got %v, want an overlap error", err)
+		}
+		if got := stub.committedTo("bank_statement"); len(got) != 0 {
+			t.Errorf("statement committed: %v", got)
+		}
+	})
+
+	t.Run("balance", func(t *testing.T) {
+		db, stub := newStubDB(t, nil)
+		m := &AccountModel{DB: db}
+		s := parse(t)
+		s.ClosingBalance += 1
+		_, err := m.ImportParsedStatement("1", 12, s)
+		if err == nil || !strings.Contains(err.Error(), "closing balance") {
+			t.Fatalf("got %v, want a closing balance error", err)
+		}
+		if got := stub.committedTo("bank_statement"); len(got) != 0 {
+			t.Errorf("statement committed: %v", got)
+		}
+	})
+}
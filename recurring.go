@@ -0,0 +1,618 @@
+package scribe
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// Recurring schedules other than cron-like expressions
+const (
+	ScheduleDaily   = "daily"
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+// parseCronField parses a comma separated list of values, ranges and
+// steps such as 1,15 or 1-5 or */2 between min and max inclusive
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %s", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %s", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %s", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%s is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func lastDayOfMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseSchedule returns a function reporting whether a date is on the
+// schedule. Weekly and monthly schedules repeat on the weekday and day of
+// month of the start date, with monthly dates moved to the last day of
+// shorter months. Cron-like expressions accept L as the last day of the
+// month and, as with cron, match either day field when both are given.
+func parseSchedule(spec string, start time.Time) (func(time.Time) bool, error) {
+	switch spec {
+	case ScheduleDaily:
+		return func(time.Time) bool { return true }, nil
+	case ScheduleWeekly:
+		return func(t time.Time) bool { return t.Weekday() == start.Weekday() }, nil
+	case ScheduleMonthly:
+		return func(t time.Time) bool {
+			day := start.Day()
+			if last := lastDayOfMonth(t); day > last {
+				day = last
+			}
+			return t.Day() == day
+		}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid schedule %q", spec)
+	}
+
+	lastDay := false
+	domField := fields[0]
+	if domField == "L" {
+		lastDay, domField = true, "*"
+	}
+	dom, err := parseCronField(domField, 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	months, err := parseCronField(fields[1], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	dow, err := parseCronField(fields[2], 0, 7)
+	if err != nil {
+		return nil, err
+	}
+	if dow[7] {
+		dow[0] = true
+	}
+
+	domAny := fields[0] == "*"
+	dowAny := fields[2] == "*"
+	return func(t time.Time) bool {
+		if !months[int(t.Month())] {
+			return false
+		}
+		domMatch := dom[t.Day()]
+		if lastDay {
+			domMatch = t.Day() == lastDayOfMonth(t)
+		}
+		dowMatch := dow[int(t.Weekday())]
+		switch {
+		case domAny && dowAny:
+			return true
+		case domAny:
+			return dowMatch
+		case dowAny:
+			return domMatch
+		default:
+			return domMatch || dowMatch
+		}
+	}, nil
+}
+
+// occurrenceDates returns the scheduled dates of a template up to and
+// including through
+func occurrenceDates(t models.RecurringTemplate, through time.Time) ([]string, error) {
+	start, err := time.Parse("2006-01-02", t.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid start date")
+	}
+	end := through
+	if t.EndDate != "" {
+		e, err := time.Parse("2006-01-02", t.EndDate)
+		if err != nil {
+			return nil, errors.New("invalid end date")
+		}
+		if e.Before(end) {
+			end = e
+		}
+	}
+
+	matches, err := parseSchedule(t.Schedule, start)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !matches(d) {
+			continue
+		}
+		if t.MaxOccurrences > 0 && len(dates) == t.MaxOccurrences {
+			break
+		}
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// insertRecurringLines stores a version of the template lines taking
+// effect from the given date and records them in the change history
+func insertRecurringLines(tx *sql.Tx, userID string, templateID int64, effectiveFrom string, lines []models.JournalEntry) error {
+	for _, l := range lines {
+		typ, amount := "DR", l.Debit
+		if l.Credit != "" {
+			typ, amount = "CR", l.Credit
+		}
+		rl := mysequel.Table{
+			TableName: "recurring_template_line",
+			Columns:   []string{"template_id", "account_id", "type", "amount", "effective_from"},
+			Vals:      []interface{}{templateID, l.Account, typ, amount, effectiveFrom},
+			Tx:        tx,
+		}
+		lid, err := mysequel.Insert(rl)
+		if err != nil {
+			return err
+		}
+		err = recordCreate(tx, userID, rl.TableName, lid, rl.Columns, rl.Vals)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRecurringLines checks that every line has either a debit or a
+// credit and that the lines balance
+func validateRecurringLines(lines []models.JournalEntry) error {
+	for _, l := range lines {
+		if (l.Debit == "") == (l.Credit == "") {
+			return errors.New("each line must have either a debit or a credit")
+		}
+	}
+	_, err := journalTotal(lines)
+	return err
+}
+
+// CreateRecurringTemplate creates a recurring journal template with its
+// initial lines, effective from the start date
+func (m *AccountModel) CreateRecurringTemplate(userID string, t models.RecurringTemplate, lines []models.JournalEntry) (int64, error) {
+	start, err := time.Parse("2006-01-02", t.StartDate)
+	if err != nil {
+		return 0, errors.New("invalid start date")
+	}
+	if t.EndDate != "" {
+		if _, err := time.Parse("2006-01-02", t.EndDate); err != nil {
+			return 0, errors.New("invalid end date")
+		}
+	}
+	if _, err := parseSchedule(t.Schedule, start); err != nil {
+		return 0, err
+	}
+	if err := validateRecurringLines(lines); err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	maxOccurrences := ""
+	if t.MaxOccurrences > 0 {
+		maxOccurrences = strconv.Itoa(t.MaxOccurrences)
+	}
+	rid, err := mysequel.Insert(mysequel.Table{
+		TableName: "recurring_template",
+		Columns:   []string{"name", "remark", "schedule", "start_date", "end_date", "max_occurrences", "user_id", "active", "datetime"},
+		Vals:      []interface{}{t.Name, t.Remark, t.Schedule, t.StartDate, t.EndDate, maxOccurrences, userID, 1, time.Now().Format("2006-01-02 15:04:05")},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertRecurringLines(tx, userID, rid, t.StartDate, lines)
+	if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+// ChangeRecurringAmounts replaces the lines of a template for occurrences
+// on or after the effective date. Earlier occurrences keep earlier lines.
+func (m *AccountModel) ChangeRecurringAmounts(userID string, templateID int64, effectiveFrom string, lines []models.JournalEntry) error {
+	if _, err := time.Parse("2006-01-02", effectiveFrom); err != nil {
+		return errors.New("invalid effective date")
+	}
+	if err := validateRecurringLines(lines); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	var id int64
+	err = tx.QueryRow(queries.RecurringTemplateForUpdate, templateID).Scan(&id)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("recurring_template %d does not exist", templateID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = insertRecurringLines(tx, userID, templateID, effectiveFrom, lines)
+	return err
+}
+
+// SetRecurringTemplateActive pauses or resumes a template
+func (m *AccountModel) SetRecurringTemplateActive(userID string, templateID int64, active bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	flag := 0
+	if active {
+		flag = 1
+	}
+	err = updateRecord(tx, userID, "recurring_template", templateID, []string{"active"}, []interface{}{flag})
+	return err
+}
+
+// RecurringTemplates returns all recurring templates
+func (m *AccountModel) RecurringTemplates() ([]models.RecurringTemplate, error) {
+	var res []models.RecurringTemplate
+	err := mysequel.QueryToStructs(&res, m.DB, queries.RecurringTemplates, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// RecurringTemplatesPage returns a page of the recurring templates
+func (m *AccountModel) RecurringTemplatesPage(cursor string, limit int) (models.RecurringTemplatePage, error) {
+	c, err := decodeCursor(cursor, 1)
+	if err != nil {
+		return models.RecurringTemplatePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.RecurringTemplatesCount).Scan(&c.Total)
+		if err != nil {
+			return models.RecurringTemplatePage{}, err
+		}
+	}
+
+	var res []models.RecurringTemplate
+	err = mysequel.QueryToStructs(&res, m.DB, queries.RecurringTemplatesPage, append(c.args(1), size+1)...)
+	if err != nil {
+		return models.RecurringTemplatePage{}, err
+	}
+
+	page := models.RecurringTemplatePage{Templates: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Templates = res[:size]
+		page.NextCursor = encodeCursor(c.Total, res[size-1].ID)
+	}
+	return page, nil
+}
+
+// GenerateRecurring posts every occurrence of the active templates that is
+// due on or before now and has not been generated yet. Each occurrence is
+// posted in its own database transaction and recorded so that it is never
+// generated twice. When postings require approval, occurrences are
+// submitted as journal drafts of the template owner instead. Failed
+// occurrences are reported and recorded with their error so that later
+// runs skip them until they are retried with RetryRecurringOccurrence.
+func (m *AccountModel) GenerateRecurring(now time.Time) (models.RecurringRunResult, error) {
+	res := models.RecurringRunResult{Generated: []models.RecurringOccurrence{}, Failed: []models.RecurringOccurrence{}}
+
+	var templates []models.RecurringTemplate
+	err := mysequel.QueryToStructs(&templates, m.DB, queries.RecurringTemplates, 1, 1)
+	if err != nil {
+		return res, err
+	}
+
+	for _, t := range templates {
+		dates, err := occurrenceDates(t, now)
+		if err != nil {
+			res.Failed = append(res.Failed, models.RecurringOccurrence{TemplateID: int64(t.ID), Error: err.Error()})
+			continue
+		}
+
+		done := make(map[string]bool)
+		rows, err := m.DB.Query(queries.RecurringOccurrenceDates, t.ID)
+		if err != nil {
+			return res, err
+		}
+		for rows.Next() {
+			var d string
+			if err := rows.Scan(&d); err != nil {
+				rows.Close()
+				return res, err
+			}
+			done[d] = true
+		}
+		rows.Close()
+
+		for _, d := range dates {
+			if done[d] {
+				continue
+			}
+			o := models.RecurringOccurrence{TemplateID: int64(t.ID), Date: d}
+			o.TransactionID, o.DraftID, err = m.generateOccurrence(t, d)
+			if err != nil {
+				o.Error = err.Error()
+				res.Failed = append(res.Failed, o)
+				if _, ferr := m.DB.Exec(queries.RecurringOccurrenceFailed, t.ID, d, o.Error); ferr != nil {
+					return res, ferr
+				}
+				continue
+			}
+			res.Generated = append(res.Generated, o)
+		}
+	}
+
+	return res, nil
+}
+
+// FailedRecurringOccurrences returns the occurrences that failed to
+// generate and are no longer attempted
+func (m *AccountModel) FailedRecurringOccurrences() ([]models.RecurringOccurrence, error) {
+	var res []models.RecurringOccurrence
+	err := mysequel.QueryToStructs(&res, m.DB, queries.FailedRecurringOccurrences)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// FailedRecurringOccurrencesPage returns a page of the failed occurrences
+func (m *AccountModel) FailedRecurringOccurrencesPage(cursor string, limit int) (models.RecurringOccurrencePage, error) {
+	c, err := decodeCursor(cursor, 2)
+	if err != nil {
+		return models.RecurringOccurrencePage{}, err
+	}
+	size := pageSize(limit)
+
+	if c.first() {
+		err = m.DB.QueryRow(queries.FailedRecurringOccurrencesCount).Scan(&c.Total)
+		if err != nil {
+			return models.RecurringOccurrencePage{}, err
+		}
+	}
+
+	var res []models.RecurringOccurrence
+	err = mysequel.QueryToStructs(&res, m.DB, queries.FailedRecurringOccurrencesPage, append(c.args(2), size+1)...)
+	if err != nil {
+		return models.RecurringOccurrencePage{}, err
+	}
+
+	page := models.RecurringOccurrencePage{Occurrences: res, TotalEstimate: c.Total}
+	if len(res) > size {
+		page.Occurrences = res[:size]
+		last := res[size-1]
+		page.NextCursor = encodeCursor(c.Total, last.TemplateID, last.Date)
+	}
+	return page, nil
+}
+
+// RetryRecurringOccurrence generates a failed occurrence again, such as
+// after the template lines were corrected. A failure is recorded again.
+func (m *AccountModel) RetryRecurringOccurrence(templateID int64, date string) (models.RecurringOccurrence, error) {
+	o := models.RecurringOccurrence{TemplateID: templateID, Date: date}
+
+	var templates []models.RecurringTemplate
+	err := mysequel.QueryToStructs(&templates, m.DB, queries.RecurringTemplate, templateID)
+	if err != nil {
+		return o, err
+	}
+	if len(templates) == 0 {
+		return o, fmt.Errorf("recurring_template %d does not exist", templateID)
+	}
+
+	r, err := m.DB.Exec(queries.DeleteFailedRecurringOccurrence, templateID, date)
+	if err != nil {
+		return o, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return o, err
+	}
+	if n == 0 {
+		return o, errors.New("occurrence has not failed")
+	}
+
+	o.TransactionID, o.DraftID, err = m.generateOccurrence(templates[0], date)
+	if err != nil {
+		o.Error = err.Error()
+		if _, ferr := m.DB.Exec(queries.RecurringOccurrenceFailed, templateID, date, o.Error); ferr != nil {
+			return o, ferr
+		}
+		return o, err
+	}
+
+	return o, nil
+}
+
+// generateOccurrence posts a single occurrence of a template, or submits
+// it as a draft when postings require approval, and returns the
+// transaction or draft ID. The occurrence is recorded first so that a
+// concurrent run generating the same date fails on the unique occurrence
+// key.
+func (m *AccountModel) generateOccurrence(t models.RecurringTemplate, date string) (int64, int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		_ = tx.Commit()
+	}()
+
+	oid, err := mysequel.Insert(mysequel.Table{
+		TableName: "recurring_occurrence",
+		Columns:   []string{"template_id", "occurrence_date"},
+		Vals:      []interface{}{t.ID, date},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var journalEntries []models.JournalEntry
+	rows, err := tx.Query(queries.RecurringLines, t.ID, t.ID, date)
+	if err != nil {
+		return 0, 0, err
+	}
+	for rows.Next() {
+		var e models.JournalEntry
+		var typ, amount string
+		if err = rows.Scan(&e.Account, &typ, &amount); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		if typ == "DR" {
+			e.Debit = amount
+		} else {
+			e.Credit = amount
+		}
+		journalEntries = append(journalEntries, e)
+	}
+	rows.Close()
+	if len(journalEntries) == 0 {
+		err = errors.New("template has no lines effective on this date")
+		return 0, 0, err
+	}
+
+	remark := t.Remark
+	if remark == "" {
+		remark = t.Name
+	}
+
+	if m.RequireApproval {
+		var entries []byte
+		entries, err = json.Marshal(journalEntries)
+		if err != nil {
+			return 0, 0, err
+		}
+		d := models.Draft{Type: DraftJournal, PostingDate: date, Remark: remark, Entries: string(entries)}
+		if _, _, err = draftTotal(models.DraftDetails{Type: d.Type, PostingDate: d.PostingDate, Entries: d.Entries}); err != nil {
+			return 0, 0, err
+		}
+		var did int64
+		did, err = insertDraft(tx, t.UserID, DraftStatusSubmitted, d)
+		if err != nil {
+			return 0, 0, err
+		}
+		err = updateOccurrence(tx, oid, "draft_id", did)
+		if err != nil {
+			return 0, 0, err
+		}
+		return 0, did, nil
+	}
+
+	tid, err := CreateTransaction(tx, t.UserID, date, "", remark)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = allocateDocumentNumber(tx, DocumentJournal, m.Branch, date, tid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = IssueJournalEntries(tx, tid, journalEntries)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = updateOccurrence(tx, oid, "transaction_id", tid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tid, 0, nil
+}
+
+// updateOccurrence links an occurrence to its transaction or draft
+func updateOccurrence(tx *sql.Tx, oid int64, column string, id int64) error {
+	_, err := mysequel.Update(mysequel.UpdateTable{
+		Table: mysequel.Table{
+			TableName: "recurring_occurrence",
+			Columns:   []string{column},
+			Vals:      []interface{}{id},
+			Tx:        tx,
+		},
+		WColumns: []string{"id"},
+		WVals:    []string{strconv.FormatInt(oid, 10)},
+	})
+	return err
+}
@@ -0,0 +1,154 @@
+package scribe
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+func TestOccurrenceDates(t *testing.T) {
+	tests := []struct {
+		name     string
+		template models.RecurringTemplate
+		through  string
+		want     []string
+	}{
+		{
+			name:     "daily up to max occurrences",
+			template: models.RecurringTemplate{Schedule: ScheduleDaily, StartDate: "2024-01-30", MaxOccurrences: 3},
+			through:  "2024-02-10",
+			want:     []string{"2024-01-30", "2024-01-31", "2024-02-01"},
+		},
+		{
+			name:     "weekly on the start weekday",
+			template: models.RecurringTemplate{Schedule: ScheduleWeekly, StartDate: "2024-01-03"},
+			through:  "2024-01-24",
+			want:     []string{"2024-01-03", "2024-01-10", "2024-01-17", "2024-01-24"},
+		},
+		{
+			name:     "monthly month end in a leap year",
+			template: models.RecurringTemplate{Schedule: ScheduleMonthly, StartDate: "2024-01-31"},
+			through:  "2024-05-31",
+			want:     []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30", "2024-05-31"},
+		},
+		{
+			name:     "monthly month end in a common year",
+			template: models.RecurringTemplate{Schedule: ScheduleMonthly, StartDate: "2023-01-31"},
+			through:  "2023-03-31",
+			want:     []string{"2023-01-31", "2023-02-28", "2023-03-31"},
+		},
+		{
+			name:     "monthly stops at the end date",
+			template: models.RecurringTemplate{Schedule: ScheduleMonthly, StartDate: "2024-01-15", EndDate: "2024-03-14"},
+			through:  "2024-12-31",
+			want:     []string{"2024-01-15", "2024-02-15"},
+		},
+		{
+			name:     "last day of month",
+			template: models.RecurringTemplate{Schedule: "L * *", StartDate: "2024-01-01"},
+			through:  "2024-03-31",
+			want:     []string{"2024-01-31", "2024-02-29", "2024-03-31"},
+		},
+		{
+			name:     "leap day only",
+			template: models.RecurringTemplate{Schedule: "29 2 *", StartDate: "2023-01-01"},
+			through:  "2025-12-31",
+			want:     []string{"2024-02-29"},
+		},
+		{
+			name:     "day of month step",
+			template: models.RecurringTemplate{Schedule: "*/10 * *", StartDate: "2024-01-01"},
+			through:  "2024-01-31",
+			want:     []string{"2024-01-01", "2024-01-11", "2024-01-21", "2024-01-31"},
+		},
+		{
+			name:     "month step from a value",
+			template: models.RecurringTemplate{Schedule: "1 1/3 *", StartDate: "2024-01-01"},
+			through:  "2024-12-31",
+			want:     []string{"2024-01-01", "2024-04-01", "2024-07-01", "2024-10-01"},
+		},
+		{
+			name:     "either day field matches",
+			template: models.RecurringTemplate{Schedule: "1-3 * 1", StartDate: "2024-01-01"},
+			through:  "2024-01-15",
+			want:     []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-08", "2024-01-15"},
+		},
+		{
+			name:     "sunday as seven",
+			template: models.RecurringTemplate{Schedule: "* * 7", StartDate: "2024-01-01"},
+			through:  "2024-01-14",
+			want:     []string{"2024-01-07", "2024-01-14"},
+		},
+		{
+			name:     "list of days",
+			template: models.RecurringTemplate{Schedule: "1,15 * *", StartDate: "2024-01-10"},
+			through:  "2024-02-01",
+			want:     []string{"2024-01-15", "2024-02-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			through, err := time.Parse("2006-01-02", tt.through)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := occurrenceDates(tt.template, through)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrenceDatesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template models.RecurringTemplate
+	}{
+		{"invalid start date", models.RecurringTemplate{Schedule: ScheduleDaily, StartDate: "2024-13-01"}},
+		{"invalid end date", models.RecurringTemplate{Schedule: ScheduleDaily, StartDate: "2024-01-01", EndDate: "soon"}},
+		{"too few fields", models.RecurringTemplate{Schedule: "1 *", StartDate: "2024-01-01"}},
+		{"day out of range", models.RecurringTemplate{Schedule: "32 * *", StartDate: "2024-01-01"}},
+		{"month out of range", models.RecurringTemplate{Schedule: "1 13 *", StartDate: "2024-01-01"}},
+		{"weekday out of range", models.RecurringTemplate{Schedule: "* * 8", StartDate: "2024-01-01"}},
+		{"zero step", models.RecurringTemplate{Schedule: "*/0 * *", StartDate: "2024-01-01"}},
+		{"reversed range", models.RecurringTemplate{Schedule: "5-1 * *", StartDate: "2024-01-01"}},
+		{"not a number", models.RecurringTemplate{Schedule: "x * *", StartDate: "2024-01-01"}},
+	}
+
+	through := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := occurrenceDates(tt.template, through); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestGenerateOccurrenceDraftFailure(t *testing.T) {
+	db, stub := newStubDB(t, map[string][][]driver.Value{
+		queries.RecurringLines: {{"1", "DR", "100.00"}, {"2", "CR", "100.00"}},
+	})
+	m := &AccountModel{DB: db, RequireApproval: true}
+	template := models.RecurringTemplate{ID: 1, UserID: "1", Name: "Rent", Schedule: ScheduleMonthly, StartDate: "2000-01-31"}
+
+	// Outside the financial year so the draft is rejected
+	if _, _, err := m.generateOccurrence(template, "2000-01-31"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := stub.committedTo("recurring_occurrence"); len(got) != 0 {
+		t.Errorf("occurrence committed: %v", got)
+	}
+	if stub.rollbacks == 0 {
+		t.Error("transaction was not rolled back")
+	}
+}
@@ -0,0 +1,117 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ssrdive/mysequel"
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// validateReversalDate checks that a reversal falls after the posting date
+// of the transaction it reverses
+func validateReversalDate(postingDate, reverseOn string) error {
+	p, err := time.Parse("2006-01-02", postingDate)
+	if err != nil {
+		return errors.New("invalid posting date")
+	}
+	r, err := time.Parse("2006-01-02", reverseOn)
+	if err != nil {
+		return errors.New("invalid reversal date")
+	}
+	if !r.After(p) {
+		return errors.New("reversal date must be after the posting date")
+	}
+	return nil
+}
+
+// issueReversal posts a transaction swapping the debits and credits of
+// the journal entries of transaction tid and links the two transactions.
+// The reversal belongs to the same contract and vendor as the original.
+func issueReversal(tx *sql.Tx, branch, userID string, tid int64, reverseOn, remark string, journalEntries []models.JournalEntry) (int64, error) {
+	reversed := make([]models.JournalEntry, len(journalEntries))
+	for i, e := range journalEntries {
+		reversed[i] = models.JournalEntry{Account: e.Account, Debit: e.Credit, Credit: e.Debit}
+	}
+
+	var contractID string
+	err := tx.QueryRow(queries.TransactionContract, tid).Scan(&contractID)
+	if err != nil {
+		return 0, err
+	}
+
+	rid, err := CreateTransaction(tx, userID, reverseOn, contractID, fmt.Sprintf("Reversal of %d: %s", tid, remark))
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = allocateDocumentNumber(tx, DocumentJournal, branch, reverseOn, rid)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(queries.CopyVendorTransaction, rid, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = mysequel.Insert(mysequel.Table{
+		TableName: "transaction_reversal",
+		Columns:   []string{"transaction_id", "reversal_transaction_id", "reverse_on"},
+		Vals:      []interface{}{tid, rid, reverseOn},
+		Tx:        tx,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = IssueJournalEntries(tx, rid, reversed)
+	if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+// Reversal returns the ID of the transaction reversing transaction tid, or
+// zero when it is not reversed
+func (m *AccountModel) Reversal(tid int) (int64, error) {
+	var rid int64
+	err := m.DB.QueryRow(queries.TransactionReversal, tid).Scan(&rid)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return rid, nil
+}
+
+// transactionEntries returns the lines of a posted transaction as journal
+// entries
+func transactionEntries(tx *sql.Tx, tid int64) ([]models.JournalEntry, error) {
+	rows, err := tx.Query(queries.TransactionLinesForLog, tid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.JournalEntry
+	for rows.Next() {
+		var e models.JournalEntry
+		var typ, amount string
+		if err := rows.Scan(&e.Account, &typ, &amount); err != nil {
+			return nil, err
+		}
+		if typ == "DR" {
+			e.Debit = amount
+		} else {
+			e.Credit = amount
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,75 @@
+package scribe
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ssrdive/scribe/models"
+	"github.com/ssrdive/scribe/queries"
+)
+
+// ScheduleDraft schedules a draft to be posted by PostDue on its posting
+// date. Approved drafts can always be scheduled. When approval is not
+// required, the maker can also schedule a draft directly.
+func (m *AccountModel) ScheduleDraft(userID string, id int64) error {
+	statuses := []string{DraftStatusApproved}
+	if !m.RequireApproval {
+		statuses = append(statuses, DraftStatusDraft)
+	}
+
+	return m.draftTx(id, statuses, func(tx *sql.Tx, d models.DraftDetails) error {
+		if d.Status == DraftStatusDraft {
+			if d.UserID != userID {
+				return errors.New("only the maker can schedule a draft")
+			}
+			if _, _, err := draftTotal(d); err != nil {
+				return err
+			}
+		}
+		return setDraft(tx, id, []string{"status"}, []interface{}{DraftStatusScheduled})
+	})
+}
+
+// PostDue posts every scheduled draft whose posting date is on or before
+// now. Each draft is posted in its own database transaction through the
+// same validation as JournalEntry and PaymentVoucher. Drafts that fail
+// stay scheduled with the failure recorded and are retried on the next
+// run.
+func (m *AccountModel) PostDue(now time.Time) (models.PostDueResult, error) {
+	res := models.PostDueResult{Posted: []models.PostedDraft{}, Failed: []models.FailedDraft{}}
+
+	var due []int64
+	rows, err := m.DB.Query(queries.DueDrafts, now.Format("2006-01-02"))
+	if err != nil {
+		return res, err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return res, err
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+
+	for _, id := range due {
+		var tid int64
+		err := m.draftTx(id, []string{DraftStatusScheduled}, func(tx *sql.Tx, d models.DraftDetails) error {
+			var err error
+			tid, err = postDraft(tx, m.Branch, "", d)
+			return err
+		})
+		if err != nil {
+			res.Failed = append(res.Failed, models.FailedDraft{DraftID: id, Error: err.Error()})
+			if _, ferr := m.DB.Exec(queries.DraftFailed, err.Error(), id); ferr != nil {
+				return res, ferr
+			}
+			continue
+		}
+		res.Posted = append(res.Posted, models.PostedDraft{DraftID: id, TransactionID: tid})
+	}
+
+	return res, nil
+}
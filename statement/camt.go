@@ -0,0 +1,186 @@
+package statement
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+type camtDate struct {
+	Dt   string `xml:"Dt"`
+	DtTm string `xml:"DtTm"`
+}
+
+// date returns the date part of either element
+func (d camtDate) date() string {
+	v := d.Dt
+	if v == "" {
+		v = d.DtTm
+	}
+	if len(v) > 10 {
+		v = v[:10]
+	}
+	return v
+}
+
+type camtAmount struct {
+	Value string `xml:",chardata"`
+	Ccy   string `xml:"Ccy,attr"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Sts       struct {
+		Value string `xml:",chardata"`
+		Cd    string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookgDt      camtDate `xml:"BookgDt"`
+	ValDt        camtDate `xml:"ValDt"`
+	AcctSvcrRef  string   `xml:"AcctSvcrRef"`
+	AddtlNtryInf string   `xml:"AddtlNtryInf"`
+	TxDtls       []struct {
+		EndToEndID string   `xml:"Refs>EndToEndId"`
+		ChqNb      string   `xml:"Refs>ChqNb"`
+		Debtor     string   `xml:"RltdPties>Dbtr>Nm"`
+		Creditor   string   `xml:"RltdPties>Cdtr>Nm"`
+		Ustrd      []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtStatement struct {
+	IBAN string        `xml:"Acct>Id>IBAN"`
+	Othr string        `xml:"Acct>Id>Othr>Id"`
+	Ccy  string        `xml:"Acct>Ccy"`
+	From string        `xml:"FrToDt>FrDtTm"`
+	To   string        `xml:"FrToDt>ToDtTm"`
+	Bal  []camtBalance `xml:"Bal"`
+	Ntry []camtEntry   `xml:"Ntry"`
+}
+
+type camtDocument struct {
+	Stmt []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// signed applies a credit or debit indicator to an amount
+func signed(value, indicator string) (float64, error) {
+	a, err := parseDecimal(value, false)
+	if err != nil {
+		return 0, err
+	}
+	if indicator == "DBIT" {
+		a = -a
+	}
+	return a, nil
+}
+
+// ParseCAMT053 reads the statements of an ISO 20022 camt.053 bank to
+// customer statement. Opening balances are taken from OPBD or PRCD
+// balances and closing balances from CLBD. Pending entries are skipped.
+func ParseCAMT053(r io.Reader) ([]Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Stmt) == 0 {
+		return nil, errors.New("no statements found")
+	}
+
+	res := make([]Statement, 0, len(doc.Stmt))
+	for _, st := range doc.Stmt {
+		s := Statement{Account: st.IBAN, Currency: st.Ccy}
+		if s.Account == "" {
+			s.Account = st.Othr
+		}
+		if len(st.From) >= 10 && len(st.To) >= 10 {
+			s.StartDate, s.EndDate = st.From[:10], st.To[:10]
+		}
+
+		var opening, closing bool
+		for _, b := range st.Bal {
+			amount, err := signed(b.Amt.Value, b.CdtDbtInd)
+			if err != nil {
+				return nil, err
+			}
+			switch b.Code {
+			case "OPBD", "PRCD":
+				if !opening {
+					s.OpeningBalance, opening = amount, true
+				}
+				// A previous closing balance is dated the day before the
+				// period, an opening balance on its first day
+				if b.Code == "OPBD" && s.StartDate == "" {
+					s.StartDate = b.Dt.date()
+				}
+			case "CLBD":
+				s.ClosingBalance, closing = amount, true
+				if s.EndDate == "" {
+					s.EndDate = b.Dt.date()
+				}
+			}
+			if s.Currency == "" {
+				s.Currency = b.Amt.Ccy
+			}
+		}
+		if !opening || !closing {
+			return nil, fmt.Errorf("statement %s lacks an opening or closing balance", s.Account)
+		}
+
+		for _, e := range st.Ntry {
+			// Version 2 has the status code directly, later versions in Cd
+			status := strings.TrimSpace(e.Sts.Value)
+			if e.Sts.Cd != "" {
+				status = e.Sts.Cd
+			}
+			if status == "PDNG" {
+				continue
+			}
+
+			var l models.BankStatementLine
+			var err error
+			if l.Amount, err = signed(e.Amt.Value, e.CdtDbtInd); err != nil {
+				return nil, err
+			}
+			if l.Date = e.BookgDt.date(); l.Date == "" {
+				l.Date = e.ValDt.date()
+			}
+			l.Reference = e.AcctSvcrRef
+			description := []string{e.AddtlNtryInf}
+			for _, t := range e.TxDtls {
+				if l.ChequeNumber == "" {
+					l.ChequeNumber = t.ChqNb
+				}
+				// The counterparty pays credits and is paid by debits
+				if l.Counterparty == "" {
+					l.Counterparty = t.Creditor
+					if l.Amount > 0 {
+						l.Counterparty = t.Debtor
+					}
+				}
+				if l.Reference == "" && t.EndToEndID != "NOTPROVIDED" {
+					l.Reference = t.EndToEndID
+				}
+				description = append(description, t.Ustrd...)
+			}
+			l.Description = strings.TrimSpace(strings.Join(description, " "))
+			s.Lines = append(s.Lines, l)
+		}
+
+		if err := verify(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
@@ -0,0 +1,90 @@
+package statement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+<BkToCstmrStmt>
+<Stmt>
+<Id>STMT-1</Id>
+<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+<Bal>
+<Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp>
+<Amt Ccy="EUR">1000.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+<Dt><Dt>2024-03-01</Dt></Dt>
+</Bal>
+<Bal>
+<Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp>
+<Amt Ccy="EUR">1150.25</Amt><CdtDbtInd>CRDT</CdtDbtInd>
+<Dt><Dt>2024-03-31</Dt></Dt>
+</Bal>
+<Ntry>
+<Amt Ccy="EUR">200.25</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+<BookgDt><Dt>2024-03-04</Dt></BookgDt><ValDt><Dt>2024-03-05</Dt></ValDt>
+<AcctSvcrRef>REF1</AcctSvcrRef>
+<NtryDtls><TxDtls>
+<Refs><EndToEndId>E2E1</EndToEndId></Refs>
+<RltdPties><Dbtr><Nm>Acme Ltd</Nm></Dbtr><Cdtr><Nm>Us</Nm></Cdtr></RltdPties>
+<RmtInf><Ustrd>Invoice 42</Ustrd></RmtInf>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+<ValDt><DtTm>2024-03-09T10:00:00</DtTm></ValDt>
+<AddtlNtryInf>Cheque</AddtlNtryInf>
+<NtryDtls><TxDtls>
+<Refs><EndToEndId>NOTPROVIDED</EndToEndId><ChqNb>000777</ChqNb></Refs>
+<RltdPties><Cdtr><Nm>City Power</Nm></Cdtr></RltdPties>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">999.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts>
+<BookgDt><Dt>2024-03-30</Dt></BookgDt>
+</Ntry>
+</Stmt>
+</BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT053(t *testing.T) {
+	want := Statement{Account: "DE89370400440532013000", Currency: "EUR", BankStatement: models.BankStatement{
+		StartDate: "2024-03-01", EndDate: "2024-03-31", OpeningBalance: 1000, ClosingBalance: 1150.25,
+		Lines: []models.BankStatementLine{
+			{Date: "2024-03-04", Description: "Invoice 42", Counterparty: "Acme Ltd", Reference: "REF1", Amount: 200.25},
+			{Date: "2024-03-09", Description: "Cheque", Counterparty: "City Power", ChequeNumber: "000777", Amount: -50},
+		},
+	}}
+
+	got, err := ParseCAMT053(strings.NewReader(camt053))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCAMT053Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"no statements", "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>"},
+		{"no closing balance", strings.Replace(camt053, "<Cd>CLBD</Cd>", "<Cd>ITBD</Cd>", 1)},
+		{"lines do not add up", strings.Replace(camt053, "1150.25", "1150.00", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCAMT053(strings.NewReader(tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package statement
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+// CSVLayout describes a bank's CSV export. Columns are named by their
+// header and matched case-insensitively. Amounts come either from a
+// signed Amount column or from separate Debit and Credit columns, debits
+// being money paid out.
+//
+// When the layout has a Balance column the opening balance is derived from
+// the first line and every running balance is checked. Otherwise the
+// opening balance is zero, the lines are not verified against any balance
+// and callers adjust the balances from their own records.
+type CSVLayout struct {
+	Delimiter    rune   `json:"delimiter"`
+	DateFormat   string `json:"date_format"`
+	DecimalComma bool   `json:"decimal_comma"`
+	NewestFirst  bool   `json:"newest_first"`
+	Date         string `json:"date"`
+	Description  string `json:"description"`
+	Counterparty string `json:"counterparty"`
+	Reference    string `json:"reference"`
+	ChequeNumber string `json:"cheque_number"`
+	Amount       string `json:"amount"`
+	Debit        string `json:"debit"`
+	Credit       string `json:"credit"`
+	Balance      string `json:"balance"`
+}
+
+// ParseCSV reads a statement from a CSV export with the given layout
+func ParseCSV(r io.Reader, layout CSVLayout) (Statement, error) {
+	if layout.Date == "" || (layout.Amount == "" && (layout.Debit == "" || layout.Credit == "")) {
+		return Statement{}, fmt.Errorf("layout needs a date column and either an amount or debit and credit columns")
+	}
+	dateFormat := layout.DateFormat
+	if dateFormat == "" {
+		dateFormat = "2006-01-02"
+	}
+
+	cr := csv.NewReader(r)
+	if layout.Delimiter != 0 {
+		cr.Comma = layout.Delimiter
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(records) == 0 {
+		return Statement{}, fmt.Errorf("statement is empty")
+	}
+
+	index := make(map[string]int)
+	for i, h := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := index[strings.ToLower(name)]
+		if !ok {
+			return -1, fmt.Errorf("missing column %s", name)
+		}
+		return i, nil
+	}
+	cols := make(map[string]int)
+	for _, c := range []struct{ key, name string }{
+		{"date", layout.Date}, {"description", layout.Description}, {"counterparty", layout.Counterparty}, {"reference", layout.Reference}, {"cheque_number", layout.ChequeNumber},
+		{"amount", layout.Amount}, {"debit", layout.Debit}, {"credit", layout.Credit}, {"balance", layout.Balance},
+	} {
+		if cols[c.key], err = column(c.name); err != nil {
+			return Statement{}, err
+		}
+	}
+
+	rows := records[1:]
+	if layout.NewestFirst {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var s Statement
+	var balance int64
+	for n, rec := range rows {
+		// Rows are numbered from 1 excluding the header
+		row := n + 1
+		if layout.NewestFirst {
+			row = len(rows) - n
+		}
+		cell := func(key string) string {
+			if i := cols[key]; i >= 0 && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if strings.Join(rec, "") == "" {
+			continue
+		}
+
+		var l models.BankStatementLine
+		date, err := time.Parse(dateFormat, cell("date"))
+		if err != nil {
+			return Statement{}, fmt.Errorf("row %d: invalid date %q", row, cell("date"))
+		}
+		l.Date = date.Format("2006-01-02")
+		l.Description = cell("description")
+		l.Counterparty = cell("counterparty")
+		l.Reference = cell("reference")
+		l.ChequeNumber = cell("cheque_number")
+
+		if layout.Amount != "" {
+			if l.Amount, err = parseDecimal(cell("amount"), layout.DecimalComma); err != nil {
+				return Statement{}, fmt.Errorf("row %d: %v", row, err)
+			}
+		} else {
+			var debit, credit float64
+			if v := cell("debit"); v != "" {
+				if debit, err = parseDecimal(v, layout.DecimalComma); err != nil {
+					return Statement{}, fmt.Errorf("row %d: %v", row, err)
+				}
+			}
+			if v := cell("credit"); v != "" {
+				if credit, err = parseDecimal(v, layout.DecimalComma); err != nil {
+					return Statement{}, fmt.Errorf("row %d: %v", row, err)
+				}
+			}
+			l.Amount = float64(cents(credit)-cents(debit)) / 100
+		}
+
+		if layout.Balance != "" {
+			b, err := parseDecimal(cell("balance"), layout.DecimalComma)
+			if err != nil {
+				return Statement{}, fmt.Errorf("row %d: %v", row, err)
+			}
+			if len(s.Lines) == 0 {
+				balance = cents(b) - cents(l.Amount)
+				s.OpeningBalance = float64(balance) / 100
+			}
+			balance += cents(l.Amount)
+			if balance != cents(b) {
+				return Statement{}, fmt.Errorf("row %d: running balance %.2f does not follow from the previous rows", row, b)
+			}
+		} else {
+			balance += cents(l.Amount)
+		}
+		s.Lines = append(s.Lines, l)
+	}
+	s.ClosingBalance = float64(balance) / 100
+
+	if err := verify(&s); err != nil {
+		return Statement{}, err
+	}
+	return s, nil
+}
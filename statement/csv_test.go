@@ -0,0 +1,97 @@
+package statement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		layout CSVLayout
+		want   Statement
+	}{
+		{
+			name: "amount and balance",
+			doc: "\ufeffDate,Details,Payee,Ref,Amount,Balance\n" +
+				"2024-01-03,Deposit,Acme Ltd,R1,\"1,500.00\",\"2,500.00\"\n" +
+				"2024-01-05,Cheque 12,City Power,R2,-250.50,\"2,249.50\"\n",
+			layout: CSVLayout{Date: "date", Description: "details", Counterparty: "payee", Reference: "ref", Amount: "amount", Balance: "balance"},
+			want: Statement{BankStatement: models.BankStatement{
+				StartDate: "2024-01-03", EndDate: "2024-01-05", OpeningBalance: 1000, ClosingBalance: 2249.5,
+				Lines: []models.BankStatementLine{
+					{Date: "2024-01-03", Description: "Deposit", Counterparty: "Acme Ltd", Reference: "R1", Amount: 1500},
+					{Date: "2024-01-05", Description: "Cheque 12", Counterparty: "City Power", Reference: "R2", Amount: -250.5},
+				},
+			}},
+		},
+		{
+			name: "debit and credit without balance",
+			doc: "Date;Text;Debit;Credit\n" +
+				"03.01.2024;Deposit;;1.500,00\n" +
+				"\n" +
+				"05.01.2024;Fee;2,75;\n",
+			layout: CSVLayout{Delimiter: ';', DateFormat: "02.01.2006", DecimalComma: true, Date: "Date", Description: "Text", Debit: "Debit", Credit: "Credit"},
+			want: Statement{BankStatement: models.BankStatement{
+				StartDate: "2024-01-03", EndDate: "2024-01-05", ClosingBalance: 1497.25,
+				Lines: []models.BankStatementLine{
+					{Date: "2024-01-03", Description: "Deposit", Amount: 1500},
+					{Date: "2024-01-05", Description: "Fee", Amount: -2.75},
+				},
+			}},
+		},
+		{
+			name: "newest first",
+			doc: "Date,Amount,Balance\n" +
+				"2024-01-05,-50.00,150.00\n" +
+				"2024-01-04,100.00,200.00\n",
+			layout: CSVLayout{NewestFirst: true, Date: "Date", Amount: "Amount", Balance: "Balance"},
+			want: Statement{BankStatement: models.BankStatement{
+				StartDate: "2024-01-04", EndDate: "2024-01-05", OpeningBalance: 100, ClosingBalance: 150,
+				Lines: []models.BankStatementLine{
+					{Date: "2024-01-04", Amount: 100},
+					{Date: "2024-01-05", Amount: -50},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tt.doc), tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	layout := CSVLayout{Date: "Date", Amount: "Amount", Balance: "Balance"}
+	tests := []struct {
+		name   string
+		doc    string
+		layout CSVLayout
+	}{
+		{"no amount column in layout", "Date\n2024-01-01\n", CSVLayout{Date: "Date"}},
+		{"missing column", "Date,Amount\n2024-01-01,1.00\n", layout},
+		{"invalid date", "Date,Amount,Balance\n01/02/2024,1.00,1.00\n", layout},
+		{"invalid amount", "Date,Amount,Balance\n2024-01-01,abc,1.00\n", layout},
+		{"broken running balance", "Date,Amount,Balance\n2024-01-01,1.00,1.00\n2024-01-02,1.00,3.00\n", layout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSV(strings.NewReader(tt.doc), tt.layout); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
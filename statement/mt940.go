@@ -0,0 +1,198 @@
+package statement
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+var (
+	mt940Tag     = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})([\d,]+)$`)
+	mt940Line    = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)[A-Z]?([\d,]+)([NFS][A-Z0-9]{3})(.*)$`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// mt940Fields splits a message into tagged fields, joining continuation
+// lines and dropping SWIFT block headers and trailers
+func mt940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.LastIndex(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if strings.HasPrefix(line, "{") || line == "-}" || line == "-" || line == "" {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: line[len(m[0]):]})
+		} else if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	return fields, scanner.Err()
+}
+
+// mt940Date converts YYMMDD to YYYY-MM-DD
+func mt940Date(s string) string {
+	century := "20"
+	if s[0:2] >= "80" {
+		century = "19"
+	}
+	return century + s[0:2] + "-" + s[2:4] + "-" + s[4:6]
+}
+
+// parseMT940Balance parses an opening or closing balance field
+func parseMT940Balance(value string) (float64, string, string, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, "", "", fmt.Errorf("invalid balance %q", value)
+	}
+	amount, err := parseDecimal(m[4], true)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if m[1] == "D" {
+		amount = -amount
+	}
+	return amount, mt940Date(m[2]), m[3], nil
+}
+
+// parseMT940Line parses a statement line field. The booking date is used
+// when given, in the year closest to the value date.
+func parseMT940Line(value string) (models.BankStatementLine, error) {
+	first := strings.SplitN(value, "\n", 2)
+	m := mt940Line.FindStringSubmatch(first[0])
+	if m == nil {
+		return models.BankStatementLine{}, fmt.Errorf("invalid statement line %q", first[0])
+	}
+
+	var l models.BankStatementLine
+	l.Date = mt940Date(m[1])
+	if m[2] != "" {
+		year, _ := strconv.Atoi(l.Date[0:4])
+		valueMonth, _ := strconv.Atoi(m[1][2:4])
+		bookingMonth, _ := strconv.Atoi(m[2][0:2])
+		if bookingMonth-valueMonth > 6 {
+			year--
+		} else if valueMonth-bookingMonth > 6 {
+			year++
+		}
+		l.Date = fmt.Sprintf("%04d-%s-%s", year, m[2][0:2], m[2][2:4])
+	}
+
+	amount, err := parseDecimal(m[4], true)
+	if err != nil {
+		return l, err
+	}
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+	l.Amount = amount
+
+	refs := strings.SplitN(m[6], "//", 2)
+	customerRef := strings.TrimSpace(refs[0])
+	if customerRef == "NONREF" {
+		customerRef = ""
+	}
+	l.Reference = customerRef
+	if len(refs) == 2 && strings.TrimSpace(refs[1]) != "" {
+		l.Reference = strings.TrimSpace(refs[1])
+	}
+	if m[5][1:] == "CHK" {
+		l.ChequeNumber = customerRef
+	}
+	if len(first) == 2 {
+		l.Description = strings.TrimSpace(first[1])
+	}
+	return l, nil
+}
+
+// ParseMT940 reads the statements of a SWIFT MT940 message. Each :20:
+// field starts a new statement and :86: information is added to the
+// description of the preceding line.
+func ParseMT940(r io.Reader) ([]Statement, error) {
+	fields, err := mt940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Statement
+	var s *Statement
+	var opening, closing bool
+	var openingDate string
+	finish := func() error {
+		if s == nil {
+			return nil
+		}
+		if !opening || !closing {
+			return fmt.Errorf("statement %s lacks an opening or closing balance", s.Account)
+		}
+		// The opening balance is usually dated at the previous closing, so
+		// the period starts with the first line unless there are none
+		if len(s.Lines) == 0 {
+			s.StartDate = openingDate
+		}
+		if err := verify(s); err != nil {
+			return err
+		}
+		res = append(res, *s)
+		return nil
+	}
+
+	for _, f := range fields {
+		if f.tag != "20" && s == nil {
+			return nil, errors.New("MT940 statement does not start with :20:")
+		}
+		switch f.tag {
+		case "20":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			s, opening, closing = &Statement{}, false, false
+		case "25":
+			s.Account = strings.TrimSpace(f.value)
+		case "60F", "60M":
+			if s.OpeningBalance, openingDate, s.Currency, err = parseMT940Balance(f.value); err != nil {
+				return nil, err
+			}
+			opening = true
+		case "61":
+			l, err := parseMT940Line(f.value)
+			if err != nil {
+				return nil, err
+			}
+			s.Lines = append(s.Lines, l)
+		case "86":
+			if n := len(s.Lines); n > 0 {
+				info := strings.Join(strings.Fields(strings.ReplaceAll(f.value, "\n", "")), " ")
+				s.Lines[n-1].Description = strings.TrimSpace(s.Lines[n-1].Description + " " + info)
+			}
+		case "62F", "62M":
+			var date string
+			if s.ClosingBalance, date, _, err = parseMT940Balance(f.value); err != nil {
+				return nil, err
+			}
+			s.EndDate, closing = date, true
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no statements found")
+	}
+	return res, nil
+}
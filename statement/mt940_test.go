@@ -0,0 +1,79 @@
+package statement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+const mt940 = `{1:F01ABNANL2AXXXX0000000000}{2:I940ABNANL2AXXXXN}{4:
+:20:STMT1
+:25:NL91ABNA0417164300
+:28C:1/1
+:60F:C231229EUR1000,00
+:61:2312310102C500,00NTRFREF1//BANKREF1
+Payment
+:86:Invoice 42 from
+ Acme Ltd
+:61:2401020102D100,50NCHK000321//BR2
+:86:Cheque
+:61:2401031231RC20,00NTRFNONREF
+:61:240104RD10,00NTRFNONREF
+:62F:C240104EUR1389,50
+:20:STMT2
+:25:NL91ABNA0417164300
+:60F:C240104EUR1389,50
+:62F:C240105EUR1389,50
+-}
+`
+
+func TestParseMT940(t *testing.T) {
+	want := []Statement{
+		{Account: "NL91ABNA0417164300", Currency: "EUR", BankStatement: models.BankStatement{
+			StartDate: "2023-12-31", EndDate: "2024-01-04", OpeningBalance: 1000, ClosingBalance: 1389.5,
+			Lines: []models.BankStatementLine{
+				// Booked in the new year for a December value date
+				{Date: "2024-01-02", Description: "Payment Invoice 42 from Acme Ltd", Reference: "BANKREF1", Amount: 500},
+				{Date: "2024-01-02", Description: "Cheque", Reference: "BR2", ChequeNumber: "000321", Amount: -100.5},
+				// Reversal of a credit booked in the old year
+				{Date: "2023-12-31", Amount: -20},
+				// Reversal of a debit
+				{Date: "2024-01-04", Amount: 10},
+			},
+		}},
+		{Account: "NL91ABNA0417164300", Currency: "EUR", BankStatement: models.BankStatement{
+			StartDate: "2024-01-04", EndDate: "2024-01-05", OpeningBalance: 1389.5, ClosingBalance: 1389.5,
+		}},
+	}
+
+	got, err := ParseMT940(strings.NewReader(mt940))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMT940Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"empty", ""},
+		{"no :20:", ":25:NL91ABNA0417164300\n"},
+		{"no closing balance", strings.Replace(mt940, ":62F:C240104EUR1389,50\n", "", 1)},
+		{"invalid line", strings.Replace(mt940, ":61:240104RD10,00NTRFNONREF", ":61:2401X4RD10,00NTRFNONREF", 1)},
+		{"lines do not add up", strings.Replace(mt940, ":62F:C240104EUR1389,50", ":62F:C240104EUR1389,00", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMT940(strings.NewReader(tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
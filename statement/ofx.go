@@ -0,0 +1,183 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+// ofxNode is an element of an OFX document. OFX 1.x is SGML where
+// elements holding values are not closed, so both versions are read with
+// the same tolerant tokenizer rather than an XML decoder.
+type ofxNode struct {
+	name     string
+	value    string
+	children []*ofxNode
+}
+
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// text returns the value at a path of child elements
+func (n *ofxNode) text(path ...string) string {
+	for _, p := range path {
+		if n = n.child(p); n == nil {
+			return ""
+		}
+	}
+	return n.value
+}
+
+// findAll returns the descendants with the given name
+func (n *ofxNode) findAll(name string) []*ofxNode {
+	var res []*ofxNode
+	for _, c := range n.children {
+		if c.name == name {
+			res = append(res, c)
+		} else {
+			res = append(res, c.findAll(name)...)
+		}
+	}
+	return res
+}
+
+func parseOFXTree(doc string) (*ofxNode, error) {
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX document")
+	}
+	doc = doc[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+	for len(doc) > 0 {
+		open := strings.IndexByte(doc, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			return nil, errors.New("unterminated OFX tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(doc[open+1 : open+end]))
+		doc = doc[open+end+1:]
+		next := strings.IndexByte(doc, '<')
+		if next < 0 {
+			next = len(doc)
+		}
+		value := strings.TrimSpace(doc[:next])
+
+		top := stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+		case strings.HasPrefix(tag, "/"):
+			name := tag[1:]
+			// Closing a value element of OFX 2 is a no-op, closing an
+			// aggregate pops up to it
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		case value != "":
+			top.children = append(top.children, &ofxNode{name: tag, value: value})
+		default:
+			n := &ofxNode{name: tag}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		}
+	}
+	return root, nil
+}
+
+// ofxDate converts an OFX date such as 20240131120000[-5:EST] to
+// YYYY-MM-DD
+func ofxDate(s string) (string, error) {
+	if len(s) < 8 {
+		return "", fmt.Errorf("invalid date %q", s)
+	}
+	return s[0:4] + "-" + s[4:6] + "-" + s[6:8], nil
+}
+
+// ofxAmount parses an OFX amount. OFX has no thousands separator and
+// allows either a point or a comma as the decimal separator.
+func ofxAmount(s string) (float64, error) {
+	return parseDecimal(s, strings.Contains(s, ","))
+}
+
+// ParseOFX reads the bank and credit card statements of an OFX or QFX
+// file. OFX gives only the closing ledger balance, so the opening balance
+// is derived from it and the transactions. The lines are therefore not
+// verified against the balances and missing transactions go unnoticed
+// until the statement is reconciled.
+func ParseOFX(r io.Reader) ([]Statement, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseOFXTree(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Statement
+	for _, rs := range append(root.findAll("STMTRS"), root.findAll("CCSTMTRS")...) {
+		s := Statement{Currency: rs.text("CURDEF")}
+		s.Account = rs.text("BANKACCTFROM", "ACCTID")
+		if s.Account == "" {
+			s.Account = rs.text("CCACCTFROM", "ACCTID")
+		}
+
+		list := rs.child("BANKTRANLIST")
+		if list == nil {
+			return nil, fmt.Errorf("statement %s has no transaction list", s.Account)
+		}
+		if s.StartDate, err = ofxDate(list.text("DTSTART")); err != nil {
+			return nil, err
+		}
+		if s.EndDate, err = ofxDate(list.text("DTEND")); err != nil {
+			return nil, err
+		}
+
+		var total int64
+		for _, t := range list.findAll("STMTTRN") {
+			var l models.BankStatementLine
+			if l.Date, err = ofxDate(t.text("DTPOSTED")); err != nil {
+				return nil, err
+			}
+			if l.Amount, err = ofxAmount(t.text("TRNAMT")); err != nil {
+				return nil, err
+			}
+			l.Reference = t.text("FITID")
+			l.ChequeNumber = t.text("CHECKNUM")
+			l.Counterparty = t.text("NAME")
+			l.Description = strings.TrimSpace(t.text("NAME") + " " + t.text("MEMO"))
+			total += cents(l.Amount)
+			s.Lines = append(s.Lines, l)
+		}
+
+		if s.ClosingBalance, err = ofxAmount(rs.text("LEDGERBAL", "BALAMT")); err != nil {
+			return nil, fmt.Errorf("statement %s has no ledger balance", s.Account)
+		}
+		s.OpeningBalance = float64(cents(s.ClosingBalance)-total) / 100
+
+		if err := verify(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no statements found")
+	}
+	return res, nil
+}
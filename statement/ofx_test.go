@@ -0,0 +1,140 @@
+package statement
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>LKR
+<BANKACCTFROM>
+<BANKID>7010
+<ACCTID>1002003
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240105120000[+5:30]
+<TRNAMT>1500.00
+<FITID>T1
+<NAME>ACME LTD
+<MEMO>Invoice 42
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CHECK
+<DTPOSTED>20240110
+<TRNAMT>-250.50
+<FITID>T2
+<CHECKNUM>000123
+<NAME>CITY POWER
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>3249.50
+<DTASOF>20240131
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+<CREDITCARDMSGSRSV1>
+<CCSTMTTRNRS>
+<CCSTMTRS>
+<CURDEF>EUR</CURDEF>
+<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240201</DTSTART>
+<DTEND>20240229</DTEND>
+<STMTTRN>
+<TRNTYPE>DEBIT</TRNTYPE>
+<DTPOSTED>20240212</DTPOSTED>
+<TRNAMT>-12,50</TRNAMT>
+<FITID>C1</FITID>
+<NAME>CAFE</NAME>
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>-112,50</BALAMT><DTASOF>20240229</DTASOF></LEDGERBAL>
+</CCSTMTRS>
+</CCSTMTTRNRS>
+</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want Statement
+	}{
+		{
+			name: "sgml",
+			doc:  ofxSGML,
+			want: Statement{Account: "1002003", Currency: "LKR", BankStatement: models.BankStatement{
+				StartDate: "2024-01-01", EndDate: "2024-01-31", OpeningBalance: 2000, ClosingBalance: 3249.5,
+				Lines: []models.BankStatementLine{
+					{Date: "2024-01-05", Description: "ACME LTD Invoice 42", Counterparty: "ACME LTD", Reference: "T1", Amount: 1500},
+					{Date: "2024-01-10", Description: "CITY POWER", Counterparty: "CITY POWER", Reference: "T2", ChequeNumber: "000123", Amount: -250.5},
+				},
+			}},
+		},
+		{
+			name: "xml with decimal comma",
+			doc:  ofxXML,
+			want: Statement{Account: "4111", Currency: "EUR", BankStatement: models.BankStatement{
+				StartDate: "2024-02-01", EndDate: "2024-02-29", OpeningBalance: -100, ClosingBalance: -112.5,
+				Lines: []models.BankStatementLine{
+					{Date: "2024-02-12", Description: "CAFE", Counterparty: "CAFE", Reference: "C1", Amount: -12.5},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOFX(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOFXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not ofx", "hello"},
+		{"no statements", "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>"},
+		{"no ledger balance", strings.Replace(ofxSGML, "<BALAMT>3249.50", "", 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseOFX(strings.NewReader(tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
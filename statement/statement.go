@@ -0,0 +1,93 @@
+// Package statement parses bank statements in OFX, CAMT.053, MT940 and
+// CSV formats into the statement model used by bank reconciliation
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ssrdive/scribe/models"
+)
+
+// Statement file formats read by Parse. CSV statements are read with
+// ParseCSV as they need a layout.
+const (
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+	FormatMT940   = "mt940"
+)
+
+// Parse reads the statements of a file in the given format
+func Parse(r io.Reader, format string) ([]Statement, error) {
+	switch format {
+	case FormatOFX:
+		return ParseOFX(r)
+	case FormatCAMT053:
+		return ParseCAMT053(r)
+	case FormatMT940:
+		return ParseMT940(r)
+	}
+	return nil, fmt.Errorf("unknown statement format %s", format)
+}
+
+// Statement is a parsed bank statement with the account number and
+// currency given by the bank. AccountID is left for the caller to set to
+// the bank account in the chart of accounts.
+type Statement struct {
+	Account  string
+	Currency string
+	models.BankStatement
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// parseDecimal parses an amount written with either a decimal point or,
+// when decimalComma is set, a decimal comma. Thousands separators and
+// spaces are ignored.
+func parseDecimal(s string, decimalComma bool) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if decimalComma {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return float64(cents(f)) / 100, nil
+}
+
+// verify fills in a missing period from the line dates and checks that
+// the lines add up from the opening to the closing balance. The check
+// holds trivially for OFX and for CSV without a Balance column, whose
+// balances are derived from the lines.
+func verify(s *Statement) error {
+	for _, l := range s.Lines {
+		if s.StartDate == "" || l.Date < s.StartDate {
+			s.StartDate = l.Date
+		}
+		if s.EndDate == "" || l.Date > s.EndDate {
+			s.EndDate = l.Date
+		}
+	}
+	if s.StartDate == "" || s.EndDate == "" {
+		return errors.New("statement has no period")
+	}
+
+	balance := cents(s.OpeningBalance)
+	for _, l := range s.Lines {
+		balance += cents(l.Amount)
+	}
+	if balance != cents(s.ClosingBalance) {
+		return fmt.Errorf("statement %s lines add up to %.2f but the closing balance is %.2f", s.Account, float64(balance)/100, s.ClosingBalance)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package scribe
+
+import (
+	"github.com/ssrdive/scribe/models"
+)
+
+// TrialBalanceReport returns the trial balance grouped by main account, sub
+// account and category with subtotals at every level, grand totals and
+// the difference between total debits and credits. Accounts with neither
+// a debit nor a credit balance are left out when hideZero is set.
+func (m *AccountModel) TrialBalanceReport(postingDate string, hideZero bool) (models.TrialBalanceReport, error) {
+	entries, err := m.TrialBalance(postingDate)
+	if err != nil {
+		return models.TrialBalanceReport{}, err
+	}
+
+	return trialBalanceReport(postingDate, entries, hideZero), nil
+}
+
+// trialBalanceReport groups consecutive entries sharing a main account, sub
+// account and category, then totals every group. Totals are accumulated in
+// cents.
+func trialBalanceReport(postingDate string, entries []models.TrialEntry, hideZero bool) models.TrialBalanceReport {
+	report := models.TrialBalanceReport{PostingDate: postingDate, MainAccounts: []models.TrialBalanceMainAccount{}}
+
+	for _, e := range entries {
+		if hideZero && toCents(e.Debit) == 0 && toCents(e.Credit) == 0 {
+			continue
+		}
+
+		mains := report.MainAccounts
+		if len(mains) == 0 || mains[len(mains)-1].Name != e.MainAccount {
+			report.MainAccounts = append(mains, models.TrialBalanceMainAccount{Name: e.MainAccount})
+		}
+		ma := &report.MainAccounts[len(report.MainAccounts)-1]
+
+		if len(ma.SubAccounts) == 0 || ma.SubAccounts[len(ma.SubAccounts)-1].Name != e.SubAccount {
+			ma.SubAccounts = append(ma.SubAccounts, models.TrialBalanceSubAccount{Name: e.SubAccount})
+		}
+		sa := &ma.SubAccounts[len(ma.SubAccounts)-1]
+
+		if len(sa.Categories) == 0 || sa.Categories[len(sa.Categories)-1].Name != e.AccountCategory {
+			sa.Categories = append(sa.Categories, models.TrialBalanceCategory{Name: e.AccountCategory})
+		}
+		c := &sa.Categories[len(sa.Categories)-1]
+		c.Accounts = append(c.Accounts, e)
+	}
+
+	var debit, credit int64
+	for mi := range report.MainAccounts {
+		ma := &report.MainAccounts[mi]
+		var maDebit, maCredit int64
+		for si := range ma.SubAccounts {
+			sa := &ma.SubAccounts[si]
+			var saDebit, saCredit int64
+			for ci := range sa.Categories {
+				c := &sa.Categories[ci]
+				var cDebit, cCredit int64
+				for _, a := range c.Accounts {
+					cDebit += toCents(a.Debit)
+					cCredit += toCents(a.Credit)
+				}
+				c.Debit, c.Credit = fromCents(cDebit), fromCents(cCredit)
+				saDebit += cDebit
+				saCredit += cCredit
+			}
+			sa.Debit, sa.Credit = fromCents(saDebit), fromCents(saCredit)
+			maDebit += saDebit
+			maCredit += saCredit
+		}
+		ma.Debit, ma.Credit = fromCents(maDebit), fromCents(maCredit)
+		debit += maDebit
+		credit += maCredit
+	}
+
+	report.Debit = fromCents(debit)
+	report.Credit = fromCents(credit)
+	report.Difference = fromCents(debit - credit)
+	report.Balanced = debit == credit
+	return report
+}